@@ -3,13 +3,14 @@ package controller
 import (
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/config"
 	"github.com/tarkov-database/tileserver/core/mbtiles"
 	"github.com/tarkov-database/tileserver/model"
 	"github.com/tarkov-database/tileserver/view"
@@ -17,23 +18,25 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-var host *url.URL
+// Controller holds the handlers of the API endpoints
+type Controller struct {
+	host *url.URL
+}
 
-func init() {
-	if env := os.Getenv("HOST_URL"); len(env) > 0 {
-		var err error
-		host, err = url.Parse(env)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, fmt.Sprintf("Error while parsing HOST_URL environment variable: %s", err))
-			os.Exit(2)
-		}
-	} else {
-		fmt.Fprintln(os.Stderr, "Host URL not set!")
-		os.Exit(2)
-	}
+// New creates a Controller by the given configuration
+func New(cfg *config.Config) *Controller {
+	return &Controller{host: cfg.Host()}
 }
 
-func IndexGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+// rootURL returns the root URL of an API, which is mounted at the first
+// segment of the request path
+func (c *Controller) rootURL(r *http.Request) *url.URL {
+	root := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+
+	return &url.URL{Scheme: c.host.Scheme, Host: c.host.Host, Path: "/" + root}
+}
+
+func (c *Controller) IndexGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	h := model.GetHealth()
 
 	if !h.OK {
@@ -43,12 +46,34 @@ func IndexGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	}
 }
 
-func TileJSONGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	r.URL.Scheme, r.URL.Host = host.Scheme, host.Host
+func (c *Controller) LivenessGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	view.RenderJSON(w, model.GetLiveness(), http.StatusOK)
+}
+
+func (c *Controller) ReadinessGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rd := model.GetReadiness(r.Context())
+
+	if rd.Status == model.Failure {
+		view.RenderJSON(w, rd, http.StatusServiceUnavailable)
+	} else {
+		view.RenderJSON(w, rd, http.StatusOK)
+	}
+}
+
+func (c *Controller) TileJSONGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	r.URL.Scheme, r.URL.Host = c.host.Scheme, c.host.Host
 
 	tj, err := model.GetTileJSON(ps.ByName("id"), r.URL)
 	if err != nil {
-		res := model.NewResponse("Tileset not found", http.StatusNotFound)
+		var res *model.Response
+		switch {
+		case errors.Is(err, model.ErrNoEntity):
+			res = model.NewResponse("Tileset not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrBadInput):
+			res = model.NewResponse(err.Error(), http.StatusBadRequest)
+		default:
+			res = model.NewResponse(err.Error(), http.StatusInternalServerError)
+		}
 		view.RenderJSON(w, res, res.StatusCode)
 		return
 	}
@@ -56,7 +81,97 @@ func TileJSONGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	view.RenderJSON(w, tj, http.StatusOK)
 }
 
-func TileGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (c *Controller) TilesetsGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	r.URL.Scheme, r.URL.Host = c.host.Scheme, c.host.Host
+
+	opts, err := model.ParseListOptions(r.URL.Query())
+	if err != nil {
+		res := model.NewResponse(err.Error(), http.StatusBadRequest)
+		view.RenderJSON(w, res, res.StatusCode)
+		return
+	}
+
+	view.RenderJSON(w, model.GetTilesetList(opts, r.URL), http.StatusOK)
+}
+
+func (c *Controller) QueryGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pq, err := model.ParsePointQuery(r.URL.Query())
+	if err != nil {
+		res := model.NewResponse(err.Error(), http.StatusBadRequest)
+		view.RenderJSON(w, res, res.StatusCode)
+		return
+	}
+
+	fc, err := model.QueryFeatures(ps.ByName("id"), pq)
+	if err != nil {
+		var res *model.Response
+		switch {
+		case errors.Is(err, model.ErrNoEntity):
+			res = model.NewResponse("Tileset not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrBadInput):
+			res = model.NewResponse(err.Error(), http.StatusBadRequest)
+		default:
+			res = model.NewResponse(err.Error(), http.StatusInternalServerError)
+		}
+		view.RenderJSON(w, res, res.StatusCode)
+		return
+	}
+
+	view.RenderGeoJSON(w, fc, http.StatusOK)
+}
+
+func (c *Controller) ExtractGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	opts, err := model.ParseExtractOptions(r.URL.Query())
+	if err != nil {
+		res := model.NewResponse(err.Error(), http.StatusBadRequest)
+		view.RenderJSON(w, res, res.StatusCode)
+		return
+	}
+
+	e, err := model.ExtractTileset(ps.ByName("id"), opts)
+	if err != nil {
+		var res *model.Response
+		switch {
+		case errors.Is(err, model.ErrNoEntity):
+			res = model.NewResponse("Tileset not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrBadInput):
+			res = model.NewResponse(err.Error(), http.StatusBadRequest)
+		case errors.Is(err, model.ErrTooLarge):
+			res = model.NewResponse(err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, model.ErrBusy):
+			w.Header().Set("Retry-After", "10")
+			res = model.NewResponse(err.Error(), http.StatusServiceUnavailable)
+		default:
+			res = model.NewResponse(err.Error(), http.StatusInternalServerError)
+		}
+		view.RenderJSON(w, res, res.StatusCode)
+		return
+	}
+	defer e.Close()
+
+	view.Download(w, r, e)
+}
+
+func (c *Controller) StatsGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	stats, err := model.GetTilesetStats(ps.ByName("id"))
+	if err != nil {
+		var res *model.Response
+		switch {
+		case errors.Is(err, model.ErrNoEntity):
+			res = model.NewResponse("Tileset not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrBadInput):
+			res = model.NewResponse(err.Error(), http.StatusBadRequest)
+		default:
+			res = model.NewResponse(err.Error(), http.StatusInternalServerError)
+		}
+		view.RenderJSON(w, res, res.StatusCode)
+		return
+	}
+
+	view.RenderJSON(w, stats, http.StatusOK)
+}
+
+func (c *Controller) TileGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var id, z, x, y string
 
 	for _, v := range ps {
@@ -72,15 +187,36 @@ func TileGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		}
 	}
 
-	isGrid := strings.HasSuffix(y, ".json")
+	serveTile(w, r, tileRequest{id, z, x, y})
+}
+
+// tileRequest holds the tileset ID and the tile coordinates of a request
+type tileRequest struct {
+	id, z, x, y string
+}
+
+// serveTile writes the requested tile or UTF grid
+func serveTile(w http.ResponseWriter, r *http.Request, tr tileRequest) {
+	isGrid := strings.HasSuffix(tr.y, ".json")
+	isGeoJSON := strings.HasSuffix(tr.y, ".geojson")
+
+	var layers []string
+	if v := r.URL.Query().Get("layers"); !isGrid && len(v) > 0 {
+		layers = model.ParseLayers(v)
+	}
+
+	if !isGrid && indexedNotModified(w, r, tr, layers) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
 	var err error
 	var tile *model.Tile
 
 	if isGrid {
-		tile, err = model.GetGrid(id, z, x, y)
+		tile, err = model.GetGrid(tr.id, tr.z, tr.x, tr.y)
 	} else {
-		tile, err = model.GetTile(id, z, x, y)
+		tile, err = model.GetTile(tr.id, tr.z, tr.x, tr.y)
 	}
 
 	if err != nil {
@@ -110,18 +246,184 @@ func TileGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		}
 	}
 
-	hash := hex.EncodeToString(tile.Hash[:])
+	if len(layers) > 0 {
+		if tile, err = model.FilterLayers(tile, layers); err != nil {
+			if errors.Is(err, model.ErrBadInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	if v := r.URL.Query().Get("callback"); isGrid && len(v) > 0 {
+		if tile, err = model.WrapJSONP(tile, v); err != nil {
+			if errors.Is(err, model.ErrBadInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	if isGeoJSON {
+		serveGeoJSON(w, r, tile, tr)
+		return
+	}
+
+	hash, encoding, ok := tileETag(w, r, tile)
+	if !ok {
+		http.Error(w, "No acceptable content encoding", http.StatusNotAcceptable)
+		return
+	}
+
 	if r.Header.Get("If-None-Match") == hash {
 		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
+	if tile, err = model.EncodeTile(tile, encoding); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Last-Modified", tile.Modified.Format(http.TimeFormat))
 	w.Header().Set("ETag", hash)
 
+	if !isGrid {
+		if tile.Cached {
+			w.Header().Set("X-Cache", "HIT")
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+	}
+
 	if isGrid {
 		view.Grid(w, tile, http.StatusOK)
 	} else {
 		view.Tile(w, tile, http.StatusOK)
 	}
 }
+
+// indexedNotModified reports whether the If-None-Match header matches the
+// tile. It is only checked if the hash of the tile is indexed, so that the
+// tile does not have to be read.
+func indexedNotModified(w http.ResponseWriter, r *http.Request, tr tileRequest, layers []string) bool {
+	match := r.Header.Get("If-None-Match")
+	if match == "" {
+		return false
+	}
+
+	tile, ok := model.GetIndexedTile(tr.id, tr.z, tr.x, tr.y)
+	if !ok {
+		return false
+	}
+
+	if len(layers) > 0 {
+		tile.Hash = model.LayersHash(tile.Hash, layers)
+	}
+
+	if strings.HasSuffix(tr.y, ".geojson") {
+		pixels, err := parsePixels(r)
+		return err == nil && geoJSONETag(tile, pixels) == match
+	}
+
+	hash, _, ok := tileETag(w, r, tile)
+
+	return ok && hash == match
+}
+
+// tileETag returns the ETag and the negotiated encoding of the tile. Vector
+// tiles and grids can be transcoded into any encoding, the returned bool is
+// false if none of them is acceptable.
+func tileETag(w http.ResponseWriter, r *http.Request, tile *model.Tile) (string, compression.Encoding, bool) {
+	hash := hex.EncodeToString(tile.Hash[:])
+
+	if tile.Format != mbtiles.PBF && tile.Format != mbtiles.JSON {
+		return hash, tile.Encoding, true
+	}
+
+	w.Header().Set("Vary", "Accept-Encoding")
+
+	header := r.Header.Get("Accept-Encoding")
+	if _, ok := r.Header["Accept-Encoding"]; ok && header == "" {
+		header = compression.Identity.String()
+	}
+
+	encoding, ok := compression.Negotiate(header, tile.Encoding, model.TileEncodings)
+	if !ok {
+		return "", encoding, false
+	}
+
+	// every encoding is a different representation
+	if encoding != tile.Encoding {
+		hash += "-" + encoding.String()
+	}
+
+	return hash, encoding, true
+}
+
+// geoJSONETag returns the ETag of the vector tile as GeoJSON
+func geoJSONETag(tile *model.Tile, pixels bool) string {
+	hash := hex.EncodeToString(tile.Hash[:]) + "-geojson"
+	if pixels {
+		hash += "-pixels"
+	}
+
+	return hash
+}
+
+// parsePixels parses the query parameter pixels, which requests the GeoJSON
+// coordinates of the tile grid instead of WGS84
+func parsePixels(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("pixels")
+	if len(v) == 0 {
+		return false, nil
+	}
+
+	pixels, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("invalid pixels parameter")
+	}
+
+	return pixels, nil
+}
+
+// serveGeoJSON writes the vector tile as GeoJSON
+func serveGeoJSON(w http.ResponseWriter, r *http.Request, tile *model.Tile, tr tileRequest) {
+	pixels, err := parsePixels(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash := geoJSONETag(tile, pixels)
+
+	if r.Header.Get("If-None-Match") == hash {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	tc, err := mbtiles.ParseTileCoord(tr.z, tr.x, tr.y)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fc, err := model.TileGeoJSON(tile, tc, pixels)
+	if err != nil {
+		if errors.Is(err, model.ErrBadInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Last-Modified", tile.Modified.Format(http.TimeFormat))
+	w.Header().Set("ETag", hash)
+
+	view.RenderGeoJSON(w, fc, http.StatusOK)
+}
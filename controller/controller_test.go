@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/model"
+)
+
+func TestTileETag(t *testing.T) {
+	hash := [32]byte{1, 2, 3}
+	etag := hex.EncodeToString(hash[:])
+
+	tests := []struct {
+		name     string
+		format   mbtiles.TileFormat
+		header   []string
+		want     string
+		encoding compression.Encoding
+		ok       bool
+		vary     bool
+	}{
+		{"vector without header", mbtiles.PBF, nil, etag, compression.Gzip, true, true},
+		{"vector in stored encoding", mbtiles.PBF, []string{"gzip, deflate"}, etag, compression.Gzip, true, true},
+		{"vector transcoded", mbtiles.PBF, []string{"br"}, etag + "-br", compression.Brotli, true, true},
+		{"vector empty header", mbtiles.PBF, []string{""}, etag + "-identity", compression.Identity, true, true},
+		{"vector not acceptable", mbtiles.PBF, []string{"identity;q=0"}, "", compression.Identity, false, true},
+		{"grid transcoded", mbtiles.JSON, []string{"zstd"}, etag + "-zstd", compression.Zstd, true, true},
+		{"raster", mbtiles.PNG, []string{"br"}, etag, compression.Gzip, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, v := range tt.header {
+				r.Header.Add("Accept-Encoding", v)
+			}
+			w := httptest.NewRecorder()
+
+			tile := &model.Tile{Format: tt.format, Encoding: compression.Gzip, Hash: hash}
+
+			got, encoding, ok := tileETag(w, r, tile)
+			if got != tt.want || encoding != tt.encoding || ok != tt.ok {
+				t.Errorf("tileETag() = %q, %v, %v, want %q, %v, %v", got, encoding, ok, tt.want, tt.encoding, tt.ok)
+			}
+
+			if vary := w.Header().Get("Vary") == "Accept-Encoding"; vary != tt.vary {
+				t.Errorf("Vary: Accept-Encoding set = %v, want %v", vary, tt.vary)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tarkov-database/tileserver/model"
+	"github.com/tarkov-database/tileserver/view"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func renderOGCError(w http.ResponseWriter, err error) {
+	var res *model.Response
+	if errors.Is(err, model.ErrNoEntity) {
+		res = model.NewResponse(err.Error(), http.StatusNotFound)
+	} else {
+		res = model.NewResponse(err.Error(), http.StatusInternalServerError)
+	}
+
+	view.RenderJSON(w, res, res.StatusCode)
+}
+
+func (c *Controller) LandingPageGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	view.RenderJSON(w, model.GetLandingPage(c.rootURL(r)), http.StatusOK)
+}
+
+func (c *Controller) ConformanceGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	view.RenderJSON(w, model.GetConformance(), http.StatusOK)
+}
+
+func (c *Controller) CollectionsGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	colls, err := model.GetCollections(c.rootURL(r))
+	if err != nil {
+		renderOGCError(w, err)
+		return
+	}
+
+	view.RenderJSON(w, colls, http.StatusOK)
+}
+
+func (c *Controller) CollectionGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	coll, err := model.GetCollection(ps.ByName("id"), c.rootURL(r))
+	if err != nil {
+		renderOGCError(w, err)
+		return
+	}
+
+	view.RenderJSON(w, coll, http.StatusOK)
+}
+
+func (c *Controller) CollectionTileSetsGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	sets, err := model.GetTileSets(ps.ByName("id"), c.rootURL(r))
+	if err != nil {
+		renderOGCError(w, err)
+		return
+	}
+
+	view.RenderJSON(w, sets, http.StatusOK)
+}
+
+func (c *Controller) CollectionTileSetGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	set, err := model.GetTileSet(ps.ByName("id"), ps.ByName("tms"), c.rootURL(r))
+	if err != nil {
+		renderOGCError(w, err)
+		return
+	}
+
+	view.RenderJSON(w, set, http.StatusOK)
+}
+
+func (c *Controller) CollectionTileGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, err := model.GetTileMatrixSet(ps.ByName("tms")); err != nil {
+		renderOGCError(w, err)
+		return
+	}
+
+	// the OGC API orders the coordinates as tile matrix, row and column
+	serveTile(w, r, tileRequest{
+		id: ps.ByName("id"),
+		z:  ps.ByName("z"),
+		x:  ps.ByName("col"),
+		y:  ps.ByName("row"),
+	})
+}
+
+func (c *Controller) TileMatrixSetsGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	view.RenderJSON(w, model.GetTileMatrixSets(c.rootURL(r)), http.StatusOK)
+}
+
+func (c *Controller) TileMatrixSetGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	set, err := model.GetTileMatrixSet(ps.ByName("tms"))
+	if err != nil {
+		renderOGCError(w, err)
+		return
+	}
+
+	view.RenderJSON(w, set, http.StatusOK)
+}
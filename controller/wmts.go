@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/model"
+	"github.com/tarkov-database/tileserver/view"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// wmtsTileRequest holds the parameters of a WMTS GetTile request
+type wmtsTileRequest struct {
+	layer, style, tms, format string
+	z, row, col               string
+}
+
+func renderWMTSException(w http.ResponseWriter, e *model.WMTSException) {
+	view.RenderXML(w, e, e.HTTPCode)
+}
+
+// WMTSGET handles the KVP encoded WMTS requests
+func (c *Controller) WMTSGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// parameter names are case insensitive
+	q := make(url.Values)
+	for k, v := range r.URL.Query() {
+		q[strings.ToUpper(k)] = v
+	}
+
+	service := q.Get("SERVICE")
+	if service == "" {
+		renderWMTSException(w, model.NewWMTSException(model.WMTSMissingParameter, "service", "missing parameter service"))
+		return
+	}
+	if !strings.EqualFold(service, "WMTS") {
+		renderWMTSException(w, model.NewWMTSException(model.WMTSInvalidParameter, "service", "service must be WMTS"))
+		return
+	}
+
+	switch req := q.Get("REQUEST"); req {
+	case "GetCapabilities":
+		c.WMTSCapabilitiesGET(w, r, nil)
+	case "GetTile":
+		tr := wmtsTileRequest{
+			layer:  q.Get("LAYER"),
+			style:  q.Get("STYLE"),
+			tms:    q.Get("TILEMATRIXSET"),
+			format: q.Get("FORMAT"),
+			z:      q.Get("TILEMATRIX"),
+			row:    q.Get("TILEROW"),
+			col:    q.Get("TILECOL"),
+		}
+
+		for _, name := range []string{"LAYER", "TILEMATRIXSET", "TILEMATRIX", "TILEROW", "TILECOL"} {
+			if q.Get(name) == "" {
+				name = strings.ToLower(name)
+				renderWMTSException(w, model.NewWMTSException(model.WMTSMissingParameter, name, "missing parameter "+name))
+				return
+			}
+		}
+
+		serveWMTSTile(w, r, tr)
+	case "":
+		renderWMTSException(w, model.NewWMTSException(model.WMTSMissingParameter, "request", "missing parameter request"))
+	default:
+		renderWMTSException(w, model.NewWMTSException(model.WMTSOperationNotSupported, "request", "unsupported request "+req))
+	}
+}
+
+func (c *Controller) WMTSCapabilitiesGET(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	caps, err := model.GetWMTSCapabilities(c.rootURL(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	view.RenderXML(w, caps, http.StatusOK)
+}
+
+// WMTSTileGET handles the RESTful WMTS GetTile requests
+func (c *Controller) WMTSTileGET(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	col := ps.ByName("col")
+	ext := path.Ext(col)
+
+	tr := wmtsTileRequest{
+		layer: ps.ByName("layer"),
+		style: ps.ByName("style"),
+		tms:   ps.ByName("tms"),
+		z:     ps.ByName("z"),
+		row:   ps.ByName("row"),
+		col:   strings.TrimSuffix(col, ext),
+	}
+
+	if len(ext) > 1 {
+		f, err := mbtiles.ParseTileFormat(ext[1:])
+		if err != nil {
+			renderWMTSException(w, model.NewWMTSException(model.WMTSInvalidParameter, "format", "unsupported format "+ext))
+			return
+		}
+		tr.format = f.ContentType()
+	}
+
+	serveWMTSTile(w, r, tr)
+}
+
+func serveWMTSTile(w http.ResponseWriter, r *http.Request, tr wmtsTileRequest) {
+	if e := model.ValidateWMTSLayer(tr.layer, tr.style, tr.tms, tr.format); e != nil {
+		renderWMTSException(w, e)
+		return
+	}
+
+	// only coordinates outside of the tile matrix are out of range
+	for _, p := range [...]struct{ name, value string }{
+		{"tilematrix", tr.z},
+		{"tilerow", tr.row},
+		{"tilecol", tr.col},
+	} {
+		if _, err := strconv.ParseUint(p.value, 10, 64); err != nil {
+			renderWMTSException(w, model.NewWMTSException(model.WMTSInvalidParameter, p.name, p.name+" must be a non-negative integer"))
+			return
+		}
+	}
+
+	if _, err := mbtiles.ParseTileCoord(tr.z, tr.col, tr.row); err != nil {
+		renderWMTSException(w, model.NewWMTSException(model.WMTSTileOutOfRange, "tilematrix", err.Error()))
+		return
+	}
+
+	serveTile(w, r, tileRequest{id: tr.layer, z: tr.z, x: tr.col, y: tr.row})
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+func TestWMTSGETExceptions(t *testing.T) {
+	tileset.Register("wmts", &tilesettest.Provider{Format: mbtiles.PNG})
+	t.Cleanup(func() { tileset.Unregister("wmts") })
+
+	const getTile = "?SERVICE=WMTS&REQUEST=GetTile&LAYER=wmts&TILEMATRIXSET=" + tms.ID
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		code    string
+		locator string
+	}{
+		{"missing service", "?REQUEST=GetCapabilities", http.StatusBadRequest, "MissingParameterValue", "service"},
+		{"invalid service", "?SERVICE=WMS&REQUEST=GetCapabilities", http.StatusBadRequest, "InvalidParameterValue", "service"},
+		{"missing request", "?SERVICE=WMTS", http.StatusBadRequest, "MissingParameterValue", "request"},
+		{"unsupported request", "?SERVICE=WMTS&REQUEST=GetFeatureInfo", http.StatusNotImplemented, "OperationNotSupported", "request"},
+		{"missing tile row", getTile + "&TILEMATRIX=1&TILECOL=0", http.StatusBadRequest, "MissingParameterValue", "tilerow"},
+		{"non-numeric tile matrix", getTile + "&TILEMATRIX=a&TILEROW=0&TILECOL=0", http.StatusBadRequest, "InvalidParameterValue", "tilematrix"},
+		{"non-numeric tile row", getTile + "&TILEMATRIX=1&TILEROW=a&TILECOL=0", http.StatusBadRequest, "InvalidParameterValue", "tilerow"},
+		{"negative tile col", getTile + "&TILEMATRIX=1&TILEROW=0&TILECOL=-1", http.StatusBadRequest, "InvalidParameterValue", "tilecol"},
+		{"tile row out of range", getTile + "&TILEMATRIX=1&TILEROW=2&TILECOL=0", http.StatusNotFound, "TileOutOfRange", "tilematrix"},
+		{"tile matrix out of range", getTile + "&TILEMATRIX=300&TILEROW=0&TILECOL=0", http.StatusNotFound, "TileOutOfRange", "tilematrix"},
+	}
+
+	c := &Controller{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c.WMTSGET(w, httptest.NewRequest(http.MethodGet, "/wmts"+tt.query, nil), nil)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %v, want %v", w.Code, tt.status)
+			}
+
+			var report struct {
+				Exception struct {
+					Code    string `xml:"exceptionCode,attr"`
+					Locator string `xml:"locator,attr"`
+				} `xml:"Exception"`
+			}
+			if err := xml.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("invalid exception report: %v", err)
+			}
+			if report.Exception.Code != tt.code || report.Exception.Locator != tt.locator {
+				t.Errorf("exception = %s at %s, want %s at %s", report.Exception.Code, report.Exception.Locator, tt.code, tt.locator)
+			}
+		})
+	}
+}
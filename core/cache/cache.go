@@ -0,0 +1,193 @@
+// Package cache provides a sharded in-memory tile cache with a byte budget
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	shardCount = 16
+
+	// entryOverhead is the estimated memory usage of an entry besides its data
+	entryOverhead = 128
+)
+
+// Key identifies a tile of a tileset. The generation distinguishes the
+// instances of a tileset, so that tiles read from a replaced instance
+// are never returned for its successor.
+type Key struct {
+	Tileset    string
+	Generation uint64
+	Z          uint8
+	X, Y       uint64
+}
+
+// Entry is a cached tile
+type Entry struct {
+	Data []byte
+	Hash [32]byte
+}
+
+func (e *Entry) size() int64 {
+	return int64(len(e.Data)) + entryOverhead
+}
+
+// Stats holds the counters of a Cache
+type Stats struct {
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+	Entries  int    `json:"entries"`
+	Size     int64  `json:"size"`
+	Capacity int64  `json:"capacity"`
+}
+
+// Cache is a least recently used cache of tiles. It is split into shards with
+// a separate lock and an equal part of the byte budget to reduce contention.
+type Cache struct {
+	capacity int64
+	shards   [shardCount]*shard
+
+	hits, misses atomic.Uint64
+}
+
+// New creates a Cache holding tiles up to the given size in bytes,
+// a size of zero or less disables the cache
+func New(size int64) *Cache {
+	c := &Cache{capacity: size}
+
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			capacity: size / shardCount,
+			items:    make(map[Key]*list.Element),
+			order:    list.New(),
+		}
+	}
+
+	return c
+}
+
+// Get returns the cached tile of the key
+func (c *Cache) Get(k Key) (*Entry, bool) {
+	e, ok := c.shard(k).get(k)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	return e, ok
+}
+
+// Add adds the tile of the key and evicts the least recently used tiles of
+// the shard until it fits into the budget. Tiles larger than the budget of
+// a shard are not cached.
+func (c *Cache) Add(k Key, e *Entry) {
+	c.shard(k).add(k, e)
+}
+
+// Invalidate removes all tiles of the tileset
+func (c *Cache) Invalidate(tileset string) {
+	for _, s := range c.shards {
+		s.invalidate(tileset)
+	}
+}
+
+// Stats returns the current counters of the Cache
+func (c *Cache) Stats() Stats {
+	st := Stats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Capacity: c.capacity,
+	}
+
+	for _, s := range c.shards {
+		s.mu.Lock()
+		st.Entries += len(s.items)
+		st.Size += s.size
+		s.mu.Unlock()
+	}
+
+	return st
+}
+
+func (c *Cache) shard(k Key) *shard {
+	// FNV-1a of the tileset and the coordinates
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(k.Tileset); i++ {
+		h = (h ^ uint64(k.Tileset[i])) * 1099511628211
+	}
+	for _, v := range [...]uint64{k.Generation, uint64(k.Z), k.X, k.Y} {
+		h = (h ^ v) * 1099511628211
+	}
+
+	return c.shards[h%shardCount]
+}
+
+type item struct {
+	key   Key
+	entry *Entry
+}
+
+type shard struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	items    map[Key]*list.Element
+	order    *list.List
+}
+
+func (s *shard) get(k Key) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[k]
+	if !ok {
+		return nil, false
+	}
+
+	s.order.MoveToFront(el)
+
+	return el.Value.(*item).entry, true
+}
+
+func (s *shard) add(k Key, e *Entry) {
+	if e.size() > s.capacity {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[k]; ok {
+		s.remove(el)
+	}
+
+	s.items[k] = s.order.PushFront(&item{k, e})
+	s.size += e.size()
+
+	for s.size > s.capacity {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *shard) invalidate(tileset string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, el := range s.items {
+		if k.Tileset == tileset {
+			s.remove(el)
+		}
+	}
+}
+
+// remove deletes the element, the lock must be held
+func (s *shard) remove(el *list.Element) {
+	it := el.Value.(*item)
+
+	s.order.Remove(el)
+	delete(s.items, it.key)
+	s.size -= it.entry.size()
+}
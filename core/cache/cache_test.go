@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// shardKeys returns n keys of the tileset that belong to the same shard
+func shardKeys(c *Cache, tileset string, n int) []Key {
+	var keys []Key
+
+	first := c.shard(Key{Tileset: tileset})
+	for x := uint64(0); len(keys) < n; x++ {
+		if k := (Key{Tileset: tileset, X: x}); c.shard(k) == first {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+func entry(size int) *Entry {
+	return &Entry{Data: make([]byte, size-entryOverhead)}
+}
+
+func TestEviction(t *testing.T) {
+	// every shard holds three entries of 1 KiB
+	c := New(shardCount * 3 << 10)
+	keys := shardKeys(c, "a", 4)
+
+	for _, k := range keys[:3] {
+		c.Add(k, entry(1<<10))
+	}
+
+	// the first key is used, so the second is the least recently used one
+	if _, ok := c.Get(keys[0]); !ok {
+		t.Fatal("Get() of a cached tile = false")
+	}
+	c.Add(keys[3], entry(1<<10))
+
+	for i, want := range []bool{true, false, true, true} {
+		if _, ok := c.Get(keys[i]); ok != want {
+			t.Errorf("Get(%v) = %v, want %v", keys[i], ok, want)
+		}
+	}
+
+	// a large entry evicts as many entries as needed
+	c.Add(keys[1], entry(2<<10))
+	for i, want := range []bool{false, true, false, true} {
+		if _, ok := c.Get(keys[i]); ok != want {
+			t.Errorf("after a large entry Get(%v) = %v, want %v", keys[i], ok, want)
+		}
+	}
+
+	// replacing an entry does not count it twice
+	c.Add(keys[3], entry(1<<10))
+	if st := c.Stats(); st.Entries != 2 || st.Size != 3<<10 {
+		t.Errorf("Stats() = %+v, want 2 entries of 3 KiB", st)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	const size = shardCount << 12
+	c := New(size)
+
+	for x := uint64(0); x < 1000; x++ {
+		c.Add(Key{Tileset: "a", X: x}, entry(1<<10))
+
+		if st := c.Stats(); st.Size > size {
+			t.Fatalf("Size = %v, exceeds the budget of %v", st.Size, size)
+		}
+	}
+
+	// entries larger than the budget of a shard are not cached
+	k := Key{Tileset: "large"}
+	c.Add(k, entry(size/shardCount+1))
+	if _, ok := c.Get(k); ok {
+		t.Error("entry larger than a shard cached")
+	}
+
+	// a disabled cache caches nothing
+	c = New(0)
+	c.Add(k, entry(entryOverhead))
+	if _, ok := c.Get(k); ok {
+		t.Error("disabled cache returned an entry")
+	}
+	if st := c.Stats(); st.Entries != 0 || st.Misses != 1 {
+		t.Errorf("Stats() = %+v, want no entries and a miss", st)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c := New(1 << 20)
+
+	for _, k := range []Key{
+		{Tileset: "a", Generation: 1},
+		{Tileset: "a", Generation: 2, X: 1},
+		{Tileset: "b", Generation: 1},
+	} {
+		c.Add(k, entry(1<<10))
+	}
+
+	// a tile of a replaced generation is never returned for its successor
+	if _, ok := c.Get(Key{Tileset: "a", Generation: 2}); ok {
+		t.Error("Get() returned a tile of another generation")
+	}
+
+	c.Invalidate("a")
+
+	for k, want := range map[Key]bool{
+		{Tileset: "a", Generation: 1}:       false,
+		{Tileset: "a", Generation: 2, X: 1}: false,
+		{Tileset: "b", Generation: 1}:       true,
+	} {
+		if _, ok := c.Get(k); ok != want {
+			t.Errorf("Get(%v) after Invalidate = %v, want %v", k, ok, want)
+		}
+	}
+
+	if st := c.Stats(); st.Entries != 1 || st.Size != 1<<10 {
+		t.Errorf("Stats() = %+v, want 1 entry of 1 KiB", st)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	c := New(shardCount << 14)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprint("tileset-", i%2)
+			for x := uint64(0); x < 2000; x++ {
+				k := Key{Tileset: id, Generation: uint64(i), X: x % 100}
+				if e, ok := c.Get(k); ok && len(e.Data) != 1<<10-entryOverhead {
+					t.Errorf("Get() = %v bytes, want %v", len(e.Data), 1<<10-entryOverhead)
+				}
+				c.Add(k, entry(1<<10))
+
+				if x%500 == 0 {
+					c.Invalidate(id)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	st := c.Stats()
+	if st.Size > st.Capacity || st.Hits+st.Misses != 8*2000 {
+		t.Errorf("Stats() = %+v, want a size within the capacity and %v lookups", st, 8*2000)
+	}
+}
@@ -0,0 +1,74 @@
+package compression
+
+import (
+	"container/list"
+	"sync"
+)
+
+// VariantKey identifies an encoded variant of a content by its hash
+type VariantKey struct {
+	Hash     [32]byte
+	Encoding Encoding
+}
+
+type variant struct {
+	key  VariantKey
+	data []byte
+}
+
+// Cache is a least recently used cache of encoded variants
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[VariantKey]*list.Element
+	order    *list.List
+}
+
+// NewCache creates a Cache holding up to capacity variants
+func NewCache(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		items:    make(map[VariantKey]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached variant of the key
+func (c *Cache) Get(key VariantKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+
+	return el.Value.(*variant).data, true
+}
+
+// Add adds the variant of the key and evicts the least recently used one if
+// the cache is full
+func (c *Cache) Add(key VariantKey, data []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		el.Value.(*variant).data = data
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&variant{key, data})
+
+	if c.order.Len() > c.capacity {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.items, el.Value.(*variant).key)
+	}
+}
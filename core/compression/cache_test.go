@@ -0,0 +1,74 @@
+package compression
+
+import (
+	"sync"
+	"testing"
+)
+
+func key(b byte, e Encoding) VariantKey {
+	return VariantKey{Hash: [32]byte{b}, Encoding: e}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+
+	c.Add(key(1, Gzip), []byte("a"))
+	c.Add(key(1, Brotli), []byte("b"))
+
+	// the variants of a hash are cached per encoding
+	if data, ok := c.Get(key(1, Gzip)); !ok || string(data) != "a" {
+		t.Errorf("Get() = %q, %v, want \"a\"", data, ok)
+	}
+
+	// the least recently used variant is evicted
+	c.Add(key(2, Gzip), []byte("c"))
+	if _, ok := c.Get(key(1, Brotli)); ok {
+		t.Error("least recently used variant not evicted")
+	}
+	for k, want := range map[VariantKey]string{key(1, Gzip): "a", key(2, Gzip): "c"} {
+		if data, ok := c.Get(k); !ok || string(data) != want {
+			t.Errorf("Get(%v) = %q, %v, want %q", k, data, ok, want)
+		}
+	}
+
+	// adding an existing variant replaces it and marks it as used
+	c.Add(key(1, Gzip), []byte("d"))
+	c.Add(key(3, Gzip), []byte("e"))
+	if data, ok := c.Get(key(1, Gzip)); !ok || string(data) != "d" {
+		t.Errorf("Get() = %q, %v, want \"d\"", data, ok)
+	}
+	if _, ok := c.Get(key(2, Gzip)); ok {
+		t.Error("least recently used variant not evicted")
+	}
+
+	// a cache without capacity caches nothing
+	c = NewCache(0)
+	c.Add(key(1, Gzip), []byte("a"))
+	if _, ok := c.Get(key(1, Gzip)); ok {
+		t.Error("cache without capacity returned a variant")
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	c := NewCache(16)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				k := key(byte(j%32), Encoding(i%len(encodingStrings)))
+				if data, ok := c.Get(k); ok && len(data) != 1 {
+					t.Errorf("Get() = %q, want a single byte", data)
+				}
+				c.Add(k, []byte{byte(j)})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := c.order.Len(); n != 16 || len(c.items) != n {
+		t.Errorf("%v variants in the list and %v in the map, want 16", n, len(c.items))
+	}
+}
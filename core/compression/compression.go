@@ -0,0 +1,224 @@
+// Package compression provides content coding negotiation and transcoding
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	ErrUnknownEncoding = errors.New("unknown content encoding")
+)
+
+// brotliLevel is the brotli quality used for encoding. Tiles are encoded on
+// the request path, so the level trades ratio for speed like the defaults of
+// the other encodings do.
+const brotliLevel = 5
+
+// the zstd coders are safe for concurrent use of DecodeAll and EncodeAll
+var (
+	zstdDecoder, _ = zstd.NewReader(nil)
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+)
+
+// Encoding represents an HTTP content coding
+type Encoding int
+
+const (
+	Identity Encoding = iota
+	Gzip
+	Deflate
+	Brotli
+	Zstd
+)
+
+var encodingStrings = [...]string{
+	"identity",
+	"gzip",
+	"deflate",
+	"br",
+	"zstd",
+}
+
+// String returns the content coding token of the Encoding
+func (e Encoding) String() string {
+	return encodingStrings[e]
+}
+
+// preference is the order in which encodings with the same quality are chosen
+var preference = []Encoding{Brotli, Zstd, Gzip, Deflate, Identity}
+
+// Negotiate returns the best encoding of the available ones for the given
+// Accept-Encoding header. The stored encoding is preferred among encodings
+// of equal quality because it needs no transcoding. If the header is absent,
+// the stored encoding is returned, a present but empty header has to be
+// passed as "identity". The second return value is false if none
+// of the available encodings is acceptable.
+func Negotiate(header string, stored Encoding, available []Encoding) (Encoding, bool) {
+	if header == "" {
+		return stored, true
+	}
+
+	qualities := parseAcceptEncoding(header)
+
+	quality := func(e Encoding) float64 {
+		if q, ok := qualities[e.String()]; ok {
+			return q
+		}
+		if q, ok := qualities["*"]; ok {
+			return q
+		}
+		if e == Identity {
+			return 0.001 // identity is always acceptable unless excluded
+		}
+		return 0
+	}
+
+	candidates := make([]Encoding, 0, len(available))
+	for _, e := range preference {
+		for _, a := range available {
+			if a == e && quality(e) > 0 {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Identity, false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		qi, qj := quality(candidates[i]), quality(candidates[j])
+		if qi == qj {
+			return candidates[i] == stored && candidates[j] != stored
+		}
+		return qi > qj
+	})
+
+	return candidates[0], true
+}
+
+// parseAcceptEncoding returns the quality values of the codings
+func parseAcceptEncoding(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+		if coding == "x-gzip" {
+			coding = Gzip.String()
+		}
+
+		q := 1.0
+		for _, p := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+
+		qualities[coding] = q
+	}
+
+	return qualities
+}
+
+// Transcode converts the data from one encoding into another
+func Transcode(data []byte, from, to Encoding) ([]byte, error) {
+	if from == to {
+		return data, nil
+	}
+
+	raw, err := Decode(data, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return Encode(raw, to)
+}
+
+// Decode decompresses the data of the given encoding
+func Decode(data []byte, e Encoding) ([]byte, error) {
+	var r io.Reader
+	var err error
+
+	switch e {
+	case Identity:
+		return data, nil
+	case Gzip:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case Deflate:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(data))
+	case Zstd:
+		b, err := zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s data: %w", e, err)
+		}
+		return b, nil
+	default:
+		return nil, ErrUnknownEncoding
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s data: %w", e, err)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s data: %w", e, err)
+	}
+
+	return b, nil
+}
+
+// Encode compresses the data with the given encoding
+func Encode(data []byte, e Encoding) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+
+	switch e {
+	case Identity:
+		return data, nil
+	case Gzip:
+		w, err = gzip.NewWriterLevel(&buf, gzip.DefaultCompression)
+	case Deflate:
+		w, err = zlib.NewWriterLevel(&buf, zlib.DefaultCompression)
+	case Brotli:
+		w = brotli.NewWriterLevel(&buf, brotliLevel)
+	case Zstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, ErrUnknownEncoding
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("could not encode %s data: %w", e, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("could not encode %s data: %w", e, err)
+	}
+
+	return buf.Bytes(), nil
+}
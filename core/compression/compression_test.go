@@ -0,0 +1,89 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	all := []Encoding{Identity, Gzip, Deflate, Brotli, Zstd}
+
+	tests := []struct {
+		name      string
+		header    string
+		stored    Encoding
+		available []Encoding
+		want      Encoding
+		ok        bool
+	}{
+		{"absent header", "", Gzip, all, Gzip, true},
+		{"identity only", "identity", Gzip, all, Identity, true},
+		{"single coding", "deflate", Gzip, all, Deflate, true},
+		{"stored among equals", "gzip, br, zstd", Gzip, all, Gzip, true},
+		{"preference among equals", "deflate, zstd, br", Gzip, all, Brotli, true},
+		{"x-gzip", "x-gzip", Identity, all, Gzip, true},
+		{"case insensitive", "GZIP;Q=0.5, Br;q=0.4", Identity, all, Gzip, true},
+		{"highest quality", "gzip;q=0.5, br;q=0.8, zstd;q=0.1", Gzip, all, Brotli, true},
+		{"quality over stored", "gzip;q=0.9, br", Gzip, all, Brotli, true},
+		{"excluded coding", "gzip;q=0, deflate", Gzip, all, Deflate, true},
+		{"wildcard", "*", Gzip, all, Gzip, true},
+		{"wildcard with exclusion", "*, gzip;q=0", Gzip, all, Brotli, true},
+		{"wildcard below listed", "*;q=0.1, deflate", Gzip, all, Deflate, true},
+		{"identity as fallback", "br", Gzip, []Encoding{Identity, Gzip}, Identity, true},
+		{"identity excluded", "identity;q=0, deflate;q=0.5", Gzip, all, Deflate, true},
+		{"only identity excluded", "identity;q=0", Gzip, all, Identity, false},
+		{"nothing acceptable", "br, identity;q=0", Gzip, []Encoding{Identity, Gzip}, Identity, false},
+		{"wildcard excluded", "*;q=0", Gzip, all, Identity, false},
+		{"invalid quality", "gzip;q=2, br;q=0.5", Identity, all, Gzip, true},
+		{"empty elements", " , gzip,, ", Identity, all, Gzip, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Negotiate(tt.header, tt.stored, tt.available)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Negotiate(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	raw := bytes.Repeat([]byte("tile data "), 100)
+	encodings := []Encoding{Identity, Gzip, Deflate, Brotli, Zstd}
+
+	for _, from := range encodings {
+		data, err := Encode(raw, from)
+		if err != nil {
+			t.Fatalf("Encode(%v) error = %v", from, err)
+		}
+
+		for _, to := range encodings {
+			t.Run(from.String()+" to "+to.String(), func(t *testing.T) {
+				got, err := Transcode(data, from, to)
+				if err != nil {
+					t.Fatalf("Transcode() error = %v", err)
+				}
+				if from == to && !bytes.Equal(got, data) {
+					t.Error("Transcode() changed data of the same encoding")
+				}
+
+				decoded, err := Decode(got, to)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if !bytes.Equal(decoded, raw) {
+					t.Error("transcoded data does not decode to the original")
+				}
+			})
+		}
+	}
+
+	if _, err := Transcode(raw, Gzip, Brotli); err == nil {
+		t.Error("Transcode() of invalid gzip data error = nil")
+	}
+	if _, err := Transcode(raw, Encoding(len(encodingStrings)), Gzip); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("Transcode() of an unknown encoding error = %v, want %v", err, ErrUnknownEncoding)
+	}
+}
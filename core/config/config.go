@@ -0,0 +1,455 @@
+// Package config loads and validates the configuration of the server from
+// a YAML file and environment variables
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server
+type Config struct {
+	// HostURL is the public URL of the server used in links
+	HostURL string `yaml:"host_url"`
+
+	Server    Server    `yaml:"server"`
+	Tiles     Tiles     `yaml:"tiles"`
+	CORS      CORS      `yaml:"cors"`
+	AccessLog AccessLog `yaml:"access_log"`
+
+	host *url.URL
+}
+
+// Server is the configuration of the HTTP server
+type Server struct {
+	Port        int    `yaml:"port"`
+	TLS         bool   `yaml:"tls"`
+	Certificate string `yaml:"certificate"`
+	PrivateKey  string `yaml:"private_key"`
+}
+
+// Tiles is the configuration of the tilesets
+type Tiles struct {
+	Dir            string        `yaml:"dir"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	CacheSize      int64         `yaml:"cache_size"`
+	ETagIndex      bool          `yaml:"etag_index"`
+
+	// MaxOverzoom is the number of zoom levels beyond the maximum zoom level
+	// of vector tilesets that are generated from the tiles at the maximum
+	MaxOverzoom int `yaml:"max_overzoom"`
+
+	// ExtractMaxSize limits the tile data of an extract in bytes, zero
+	// disables the extract endpoint
+	ExtractMaxSize int64 `yaml:"extract_max_size"`
+
+	// Composites maps the IDs of composite tilesets to their vector tile
+	// sources joined by "+", e.g. "customs-base + customs-labels"
+	Composites map[string]string `yaml:"composites"`
+}
+
+// CORS is the configuration of the CORS middleware
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// AccessLog is the configuration of the access log middleware
+type AccessLog struct {
+	// Format is one of json, logfmt or none
+	Format string `yaml:"format"`
+
+	// File is the path of the log file, the log is written to stdout if empty
+	File       string  `yaml:"file"`
+	MaxSize    int64   `yaml:"max_size"`
+	MaxBackups int     `yaml:"max_backups"`
+	SampleRate float64 `yaml:"sample_rate"`
+
+	// TrustedProxies are IP addresses and CIDR ranges of proxies whose
+	// X-Forwarded-For header is trusted
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Access log formats
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatNone   = "none"
+)
+
+// maxOverzoom is the upper limit of Tiles.MaxOverzoom
+const maxOverzoom = 16
+
+// Default returns the default configuration
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port: 8080,
+		},
+		Tiles: Tiles{
+			Dir:            "./tilesets",
+			ReloadInterval: 30 * time.Second,
+			CacheSize:      64 << 20,
+			MaxOverzoom:    6,
+			ExtractMaxSize: 256 << 20,
+		},
+		AccessLog: AccessLog{
+			Format:     FormatJSON,
+			MaxSize:    100 << 20,
+			MaxBackups: 5,
+			SampleRate: 1,
+		},
+	}
+}
+
+// Load reads the configuration file if the path is not empty, applies the
+// environment variables and validates the result. All errors are reported
+// together.
+func Load(file string) (*Config, error) {
+	c := Default()
+
+	if len(file) > 0 {
+		if err := c.readFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := c.applyEnv(os.Getenv)
+	errs = append(errs, c.validate()...)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Host returns the parsed host URL of a loaded configuration
+func (c *Config) Host() *url.URL {
+	return c.host
+}
+
+func (c *Config) readFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("could not open configuration file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("could not parse configuration file: %w", err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides the values of all set environment variables
+func (c *Config) applyEnv(getenv func(string) string) []error {
+	e := &envParser{getenv: getenv}
+
+	e.strVar("HOST_URL", &c.HostURL)
+
+	e.intVar("SERVER_PORT", &c.Server.Port)
+	e.boolVar("SERVER_TLS", &c.Server.TLS)
+	e.strVar("SERVER_CERT", &c.Server.Certificate)
+	e.strVar("SERVER_KEY", &c.Server.PrivateKey)
+
+	e.strVar("TILE_DIR", &c.Tiles.Dir)
+	e.durationVar("TILE_RELOAD_INTERVAL", &c.Tiles.ReloadInterval)
+	e.int64Var("TILE_CACHE_SIZE", &c.Tiles.CacheSize)
+	e.boolVar("TILE_ETAG_INDEX", &c.Tiles.ETagIndex)
+	e.intVar("TILE_MAX_OVERZOOM", &c.Tiles.MaxOverzoom)
+	e.int64Var("TILE_EXTRACT_MAX_SIZE", &c.Tiles.ExtractMaxSize)
+	e.mapVar("TILE_COMPOSITES", &c.Tiles.Composites)
+
+	e.listVar("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+
+	e.strVar("ACCESS_LOG_FORMAT", &c.AccessLog.Format)
+	e.strVar("ACCESS_LOG_FILE", &c.AccessLog.File)
+	e.int64Var("ACCESS_LOG_MAX_SIZE", &c.AccessLog.MaxSize)
+	e.intVar("ACCESS_LOG_MAX_BACKUPS", &c.AccessLog.MaxBackups)
+	e.floatVar("ACCESS_LOG_SAMPLE_RATE", &c.AccessLog.SampleRate)
+	e.listVar("TRUSTED_PROXIES", &c.AccessLog.TrustedProxies)
+
+	return e.errs
+}
+
+// envParser sets the values of environment variables that are not empty and
+// collects the parsing errors
+type envParser struct {
+	getenv func(string) string
+	errs   []error
+}
+
+func (e *envParser) parse(key string, fn func(string) error) {
+	if env := e.getenv(key); len(env) > 0 {
+		if err := fn(env); err != nil {
+			e.errs = append(e.errs, fmt.Errorf("invalid %s environment variable: %w", key, err))
+		}
+	}
+}
+
+func (e *envParser) strVar(key string, v *string) {
+	e.parse(key, func(s string) error {
+		*v = s
+		return nil
+	})
+}
+
+func (e *envParser) listVar(key string, v *[]string) {
+	e.parse(key, func(s string) error {
+		*v = splitList(s)
+		return nil
+	})
+}
+
+// mapVar parses a comma separated list of key=value pairs
+func (e *envParser) mapVar(key string, v *map[string]string) {
+	e.parse(key, func(s string) error {
+		m := make(map[string]string)
+		for _, pair := range splitList(s) {
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("missing value of %q", pair)
+			}
+			m[strings.TrimSpace(k)] = val
+		}
+		*v = m
+		return nil
+	})
+}
+
+func (e *envParser) intVar(key string, v *int) {
+	e.parse(key, func(s string) error {
+		i, err := strconv.Atoi(s)
+		if err == nil {
+			*v = i
+		}
+		return err
+	})
+}
+
+func (e *envParser) int64Var(key string, v *int64) {
+	e.parse(key, func(s string) error {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			*v = i
+		}
+		return err
+	})
+}
+
+func (e *envParser) floatVar(key string, v *float64) {
+	e.parse(key, func(s string) error {
+		f, err := strconv.ParseFloat(s, 64)
+		if err == nil {
+			*v = f
+		}
+		return err
+	})
+}
+
+func (e *envParser) boolVar(key string, v *bool) {
+	e.parse(key, func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err == nil {
+			*v = b
+		}
+		return err
+	})
+}
+
+func (e *envParser) durationVar(key string, v *time.Duration) {
+	e.parse(key, func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			*v = d
+		}
+		return err
+	})
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+
+	if len(c.HostURL) == 0 {
+		errs = append(errs, errors.New("host URL not set"))
+	} else if u, err := url.Parse(c.HostURL); err != nil {
+		errs = append(errs, fmt.Errorf("invalid host URL: %w", err))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		errs = append(errs, fmt.Errorf("invalid host URL scheme %q", u.Scheme))
+	} else {
+		c.host = u
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server port %v is out of range", c.Server.Port))
+	}
+	if c.Server.TLS {
+		if len(c.Server.Certificate) == 0 {
+			errs = append(errs, errors.New("server certificate missing"))
+		}
+		if len(c.Server.PrivateKey) == 0 {
+			errs = append(errs, errors.New("server private key missing"))
+		}
+	}
+
+	if len(c.Tiles.Dir) == 0 {
+		errs = append(errs, errors.New("tile directory not set"))
+	}
+	if c.Tiles.ReloadInterval < 0 {
+		errs = append(errs, errors.New("tile reload interval must not be negative"))
+	}
+	if c.Tiles.CacheSize < 0 {
+		errs = append(errs, errors.New("tile cache size must not be negative"))
+	}
+	if c.Tiles.MaxOverzoom < 0 || c.Tiles.MaxOverzoom > maxOverzoom {
+		errs = append(errs, fmt.Errorf("tile max overzoom %v is out of range", c.Tiles.MaxOverzoom))
+	}
+	if c.Tiles.ExtractMaxSize < 0 {
+		errs = append(errs, errors.New("tile extract max size must not be negative"))
+	}
+	if _, err := c.Tiles.CompositeSources(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch c.AccessLog.Format {
+	case FormatJSON, FormatLogfmt, FormatNone:
+	default:
+		errs = append(errs, fmt.Errorf("unknown access log format %q", c.AccessLog.Format))
+	}
+	if c.AccessLog.MaxSize < 0 {
+		errs = append(errs, errors.New("access log max size must not be negative"))
+	}
+	if c.AccessLog.MaxBackups < 0 {
+		errs = append(errs, errors.New("access log max backups must not be negative"))
+	}
+	if c.AccessLog.SampleRate < 0 || c.AccessLog.SampleRate > 1 {
+		errs = append(errs, errors.New("access log sample rate must be between 0 and 1"))
+	}
+	if _, err := c.AccessLog.TrustedNetworks(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// validateOrigin checks that the origin is an absolute HTTP or HTTPS URL
+func validateOrigin(origin string) error {
+	u, err := url.ParseRequestURI(origin)
+	if err != nil {
+		return fmt.Errorf("invalid CORS origin %q: %w", origin, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL scheme %q in CORS origin %q", u.Scheme, origin)
+	}
+
+	return nil
+}
+
+// CompositeSources parses the sources of the composite tilesets. A composite
+// needs at least two sources, which must not be composites themselves. The
+// errors of all composites are joined in the order of their IDs.
+func (t *Tiles) CompositeSources() (map[string][]string, error) {
+	ids := make([]string, 0, len(t.Composites))
+	for id := range t.Composites {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	composites := make(map[string][]string, len(t.Composites))
+
+	for _, id := range ids {
+		if len(id) == 0 {
+			errs = append(errs, errors.New("composite tileset ID must not be empty"))
+			continue
+		}
+
+		var sources []string
+		for _, src := range strings.Split(t.Composites[id], "+") {
+			if src = strings.TrimSpace(src); src != "" {
+				sources = append(sources, src)
+			}
+		}
+		if len(sources) < 2 {
+			errs = append(errs, fmt.Errorf("composite tileset %q needs at least two sources", id))
+			continue
+		}
+
+		composites[id] = sources
+	}
+
+	for _, id := range ids {
+		for _, src := range composites[id] {
+			if _, ok := t.Composites[src]; ok {
+				errs = append(errs, fmt.Errorf("composite tileset %q must not contain the composite %q", id, src))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return composites, nil
+}
+
+// TrustedNetworks parses the trusted proxy addresses and ranges
+func (a *AccessLog) TrustedNetworks() ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(a.TrustedProxies))
+
+	for _, v := range a.TrustedProxies {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", v)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range %q", v)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// splitList splits a comma separated list and drops empty values
+func splitList(s string) []string {
+	values := []string{}
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
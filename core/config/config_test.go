@@ -0,0 +1,236 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envKeys are all environment variables read by Load
+var envKeys = []string{
+	"HOST_URL", "SERVER_PORT", "SERVER_TLS", "SERVER_CERT", "SERVER_KEY",
+	"TILE_DIR", "TILE_RELOAD_INTERVAL", "TILE_CACHE_SIZE", "TILE_ETAG_INDEX",
+	"TILE_MAX_OVERZOOM", "TILE_EXTRACT_MAX_SIZE", "TILE_COMPOSITES",
+	"CORS_ALLOWED_ORIGINS", "ACCESS_LOG_FORMAT", "ACCESS_LOG_FILE",
+	"ACCESS_LOG_MAX_SIZE", "ACCESS_LOG_MAX_BACKUPS", "ACCESS_LOG_SAMPLE_RATE",
+	"TRUSTED_PROXIES",
+}
+
+// setEnv clears all environment variables of the configuration and sets the
+// given ones for the test
+func setEnv(t *testing.T, env map[string]string) {
+	for _, k := range envKeys {
+		t.Setenv(k, env[k])
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+const testFile = `
+host_url: https://tiles.example.com
+server:
+  port: 9000
+tiles:
+  dir: /srv/tiles
+  reload_interval: 1m
+  cache_size: 1024
+  composites:
+    customs: customs-base + customs-loot
+cors:
+  allowed_origins: [https://example.com]
+access_log:
+  format: logfmt
+  trusted_proxies: [10.0.0.0/8]
+`
+
+func TestLoad(t *testing.T) {
+	setEnv(t, map[string]string{
+		"SERVER_PORT":            "9100",
+		"TILE_ETAG_INDEX":        "true",
+		"TILE_RELOAD_INTERVAL":   "5s",
+		"CORS_ALLOWED_ORIGINS":   "https://a.example.com, https://b.example.com",
+		"ACCESS_LOG_SAMPLE_RATE": "0.5",
+	})
+
+	c, err := Load(writeFile(t, testFile))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := Default()
+	want.HostURL = "https://tiles.example.com"
+	want.Server.Port = 9100
+	want.Tiles.Dir = "/srv/tiles"
+	want.Tiles.ReloadInterval = 5 * time.Second
+	want.Tiles.CacheSize = 1024
+	want.Tiles.ETagIndex = true
+	want.Tiles.Composites = map[string]string{"customs": "customs-base + customs-loot"}
+	want.CORS.AllowedOrigins = []string{"https://a.example.com", "https://b.example.com"}
+	want.AccessLog.Format = FormatLogfmt
+	want.AccessLog.SampleRate = 0.5
+	want.AccessLog.TrustedProxies = []string{"10.0.0.0/8"}
+	want.host = c.host
+
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Load() =\n%+v\nwant\n%+v", c, want)
+	}
+	if c.Host() == nil || c.Host().Host != "tiles.example.com" {
+		t.Errorf("Host() = %v, want tiles.example.com", c.Host())
+	}
+
+	composites, err := c.Tiles.CompositeSources()
+	if err != nil {
+		t.Fatalf("CompositeSources() error = %v", err)
+	}
+	if want := map[string][]string{"customs": {"customs-base", "customs-loot"}}; !reflect.DeepEqual(composites, want) {
+		t.Errorf("CompositeSources() = %v, want %v", composites, want)
+	}
+}
+
+func TestLoadEnvOnly(t *testing.T) {
+	setEnv(t, map[string]string{
+		"HOST_URL":        "http://localhost:8080",
+		"TILE_COMPOSITES": "a=x+y,b=y + z",
+		"TRUSTED_PROXIES": "127.0.0.1,::1",
+	})
+
+	c, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if c.Tiles.Dir != Default().Tiles.Dir {
+		t.Errorf("Tiles.Dir = %q, want the default %q", c.Tiles.Dir, Default().Tiles.Dir)
+	}
+	if want := map[string]string{"a": "x+y", "b": "y + z"}; !reflect.DeepEqual(c.Tiles.Composites, want) {
+		t.Errorf("Tiles.Composites = %v, want %v", c.Tiles.Composites, want)
+	}
+
+	nets, err := c.AccessLog.TrustedNetworks()
+	if err != nil {
+		t.Fatalf("TrustedNetworks() error = %v", err)
+	}
+	if len(nets) != 2 || nets[0].String() != "127.0.0.1/32" || nets[1].String() != "::1/128" {
+		t.Errorf("TrustedNetworks() = %v, want [127.0.0.1/32 ::1/128]", nets)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "missing host",
+			want: []string{"host URL not set"},
+		},
+		{
+			name: "all errors are joined",
+			file: "host_url: ftp://example.com\nserver:\n  port: 70000\n",
+			env: map[string]string{
+				"TILE_CACHE_SIZE":        "big",
+				"TILE_ETAG_INDEX":        "maybe",
+				"ACCESS_LOG_FORMAT":      "xml",
+				"ACCESS_LOG_SAMPLE_RATE": "2",
+				"TILE_MAX_OVERZOOM":      "20",
+				"TILE_COMPOSITES":        "a=x",
+				"CORS_ALLOWED_ORIGINS":   "file:///etc",
+				"TRUSTED_PROXIES":        "proxy",
+			},
+			want: []string{
+				"invalid TILE_CACHE_SIZE environment variable",
+				"invalid TILE_ETAG_INDEX environment variable",
+				`invalid host URL scheme "ftp"`,
+				"server port 70000 is out of range",
+				"tile max overzoom 20 is out of range",
+				`composite tileset "a" needs at least two sources`,
+				`invalid URL scheme "file" in CORS origin "file:///etc"`,
+				`unknown access log format "xml"`,
+				"access log sample rate must be between 0 and 1",
+				`invalid trusted proxy address "proxy"`,
+			},
+		},
+		{
+			name: "TLS without certificate",
+			env:  map[string]string{"HOST_URL": "https://example.com", "SERVER_TLS": "true"},
+			want: []string{"server certificate missing", "server private key missing"},
+		},
+		{
+			name: "nested composite",
+			env:  map[string]string{"HOST_URL": "https://example.com", "TILE_COMPOSITES": "a=x+y,b=a+z"},
+			want: []string{`composite tileset "b" must not contain the composite "a"`},
+		},
+		{
+			name: "unknown field",
+			file: "tiles:\n  directory: /srv/tiles\n",
+			want: []string{"could not parse configuration file", "field directory not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+
+			file := ""
+			if tt.file != "" {
+				file = writeFile(t, tt.file)
+			}
+
+			_, err := Load(file)
+			if err == nil {
+				t.Fatal("Load() error = nil")
+			}
+
+			for _, msg := range tt.want {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("error %q does not contain %q", err, msg)
+				}
+			}
+
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				if n := len(joined.Unwrap()); n != len(tt.want) {
+					t.Errorf("%v joined errors, want %v", n, len(tt.want))
+				}
+			}
+		})
+	}
+}
+
+func TestCompositeSources(t *testing.T) {
+	tiles := &Tiles{Composites: map[string]string{
+		"a": "x + y",
+		"b": "x",
+		"c": "a+z",
+		"d": "",
+		"e": "b+c+y",
+	}}
+
+	want := `composite tileset "b" needs at least two sources
+composite tileset "d" needs at least two sources
+composite tileset "c" must not contain the composite "a"
+composite tileset "e" must not contain the composite "b"
+composite tileset "e" must not contain the composite "c"`
+
+	// the order of the errors does not depend on the iteration of the map
+	for i := 0; i < 10; i++ {
+		composites, err := tiles.CompositeSources()
+		if err == nil || err.Error() != want {
+			t.Fatalf("CompositeSources() error =\n%v\nwant\n%s", err, want)
+		}
+		if composites != nil {
+			t.Errorf("CompositeSources() = %v, want nil", composites)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package mbtiles
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+// ETagIndexSuffix is appended to the path of an MBTiles file to get the path
+// of its ETag index
+const ETagIndexSuffix = ".etag"
+
+// The ETag index is a SQLite database holding the BLAKE3 hash of every tile.
+// The modification time and size of the MBTiles file it was built from are
+// stored in the source table, an index of a changed file is ignored.
+const (
+	etagSchema = `CREATE TABLE source (modified INTEGER NOT NULL, size INTEGER NOT NULL);
+CREATE TABLE etags (
+	zoom_level INTEGER NOT NULL,
+	tile_column INTEGER NOT NULL,
+	tile_row INTEGER NOT NULL,
+	hash BLOB NOT NULL,
+	PRIMARY KEY (zoom_level, tile_column, tile_row)
+) WITHOUT ROWID;`
+
+	etagInsert = "INSERT OR REPLACE INTO etags (zoom_level, tile_column, tile_row, hash) VALUES (?, ?, ?, ?)"
+)
+
+// sqliteURI returns the URI of the SQLite database file with the given access
+// mode. The path is made absolute and escaped, so that characters like "?",
+// "#" and "%" are part of the file name.
+func sqliteURI(file, mode string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // a Windows drive letter
+	}
+
+	u := &url.URL{Scheme: "file", Path: p, RawQuery: url.Values{"mode": {mode}}.Encode()}
+
+	return u.String(), nil
+}
+
+// openETagIndex opens the ETag index of the Tileset if it is up to date
+func (ts *Tileset) openETagIndex() error {
+	file := ts.path + ETagIndexSuffix
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+
+	dsn, err := sqliteURI(file, "ro")
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+
+	var modified, size int64
+	if err := db.QueryRow("SELECT modified, size FROM source").Scan(&modified, &size); err != nil {
+		db.Close()
+		return fmt.Errorf("could not read etag index source: %w", err)
+	}
+
+	if modified != ts.modified.UnixNano() || size != ts.size {
+		db.Close()
+		return fmt.Errorf("etag index is outdated")
+	}
+
+	if old := ts.etags.Swap(db); old != nil {
+		old.Close()
+	}
+
+	return nil
+}
+
+// HasETagIndex reports whether the Tileset has an up to date ETag index
+func (ts *Tileset) HasETagIndex() bool {
+	return ts.etags.Load() != nil
+}
+
+// TileHash returns the hash of a tile from the ETag index. The second return
+// value is false if there is no index or the tile is not indexed.
+func (ts *Tileset) TileHash(tc *TileCoord) ([32]byte, bool) {
+	var sum [32]byte
+
+	db := ts.etags.Load()
+	if db == nil {
+		return sum, false
+	}
+
+	var hash []byte
+	if err := db.QueryRow("SELECT hash FROM etags WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?", tc.Z, tc.X, tc.Y).
+		Scan(&hash); err != nil || len(hash) != len(sum) {
+		return sum, false
+	}
+
+	copy(sum[:], hash)
+
+	return sum, true
+}
+
+// BuildETagIndex hashes all tiles and writes the hashes into the ETag index
+// next to the MBTiles file. Tiles of the images and map deduplication schema
+// are hashed once per tile_id. The index is written to a temporary file
+// and replaces the existing index once it is complete.
+func (ts *Tileset) BuildETagIndex() (err error) {
+	file := ts.path + ETagIndexSuffix
+	tmp := file + ".tmp"
+
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	dsn, err := sqliteURI(tmp, "rwc")
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		db.Close()
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	if _, err = db.Exec(etagSchema); err != nil {
+		return fmt.Errorf("could not create etag index: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("INSERT INTO source (modified, size) VALUES (?, ?)", ts.modified.UnixNano(), ts.size); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(etagInsert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var dedup int
+	if err = ts.database.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('images', 'map')").
+		Scan(&dedup); err != nil {
+		return err
+	}
+
+	if dedup == 2 {
+		err = ts.hashImages(stmt)
+	} else {
+		err = ts.hashTiles(stmt)
+	}
+	if err != nil {
+		return fmt.Errorf("could not hash tiles: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if err = db.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, file); err != nil {
+		return err
+	}
+
+	return ts.openETagIndex()
+}
+
+// hashTiles inserts the hash of every row of the tiles table
+func (ts *Tileset) hashTiles(stmt *sql.Stmt) error {
+	rows, err := ts.database.Query("SELECT zoom_level, tile_column, tile_row, tile_data FROM tiles")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var z, x, y int64
+	var data []byte
+	for rows.Next() {
+		if err := rows.Scan(&z, &x, &y, &data); err != nil {
+			return err
+		}
+
+		sum := blake3.Sum256(data)
+		if _, err := stmt.Exec(z, x, y, sum[:]); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// hashImages hashes every image once and inserts the hash for all tiles
+// referencing it
+func (ts *Tileset) hashImages(stmt *sql.Stmt) error {
+	sums := make(map[string][32]byte)
+
+	rows, err := ts.database.Query("SELECT tile_id, tile_data FROM images")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id string
+	var data []byte
+	for rows.Next() {
+		if err := rows.Scan(&id, &data); err != nil {
+			return err
+		}
+		sums[id] = blake3.Sum256(data)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = ts.database.Query("SELECT zoom_level, tile_column, tile_row, tile_id FROM map")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var z, x, y int64
+	for rows.Next() {
+		if err := rows.Scan(&z, &x, &y, &id); err != nil {
+			return err
+		}
+
+		sum, ok := sums[id]
+		if !ok {
+			continue // the tile has no image
+		}
+
+		if _, err := stmt.Exec(z, x, y, sum[:]); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
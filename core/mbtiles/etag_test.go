@@ -0,0 +1,88 @@
+package mbtiles
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeebo/blake3"
+)
+
+func TestSQLiteURI(t *testing.T) {
+	got, err := sqliteURI("/data/a b?#%.mbtiles", "ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "file:///data/a%20b%3F%23%25.mbtiles?mode=ro"; got != want {
+		t.Errorf("sqliteURI() = %q, want %q", got, want)
+	}
+
+	// relative paths are not taken as host
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = sqliteURI("a.mbtiles", "rwc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "file://" + filepath.ToSlash(wd) + "/a.mbtiles?mode=rwc"; got != want {
+		t.Errorf("sqliteURI() = %q, want %q", got, want)
+	}
+}
+
+func TestETagIndex(t *testing.T) {
+	// the characters have a meaning in URIs
+	dir := filepath.Join(t.TempDir(), "a #b%20c")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "tiles.mbtiles")
+
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE metadata (name text, value text)",
+		"CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)",
+		"INSERT INTO metadata VALUES ('name', 'etags'), ('format', 'png')",
+		"INSERT INTO tiles VALUES (0, 0, 0, x'89504e470d0a1a0a00')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	ts, err := NewTileset(file)
+	if err != nil {
+		t.Fatalf("NewTileset() error = %v", err)
+	}
+	if ts.HasETagIndex() {
+		t.Error("HasETagIndex() = true before the index is built")
+	}
+	if err := ts.BuildETagIndex(); err != nil {
+		t.Fatalf("BuildETagIndex() error = %v", err)
+	}
+	ts.Close()
+
+	// the index is opened with the tileset
+	if ts, err = NewTileset(file); err != nil {
+		t.Fatalf("NewTileset() error = %v", err)
+	}
+	defer ts.Close()
+
+	if !ts.HasETagIndex() {
+		t.Fatal("HasETagIndex() = false after the index was built")
+	}
+
+	want := blake3.Sum256([]byte("\x89PNG\r\n\x1a\n\x00"))
+	if got, ok := ts.TileHash(&TileCoord{}); !ok || got != want {
+		t.Errorf("TileHash() = %x, %v, want %x", got, ok, want)
+	}
+	if _, ok := ts.TileHash(&TileCoord{Z: 1}); ok {
+		t.Error("TileHash() of a missing tile = true")
+	}
+}
@@ -0,0 +1,205 @@
+package mbtiles
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+var ErrExtractTooLarge = errors.New("extract exceeds the size limit")
+
+const extractSchema = `CREATE TABLE metadata (name TEXT NOT NULL, value TEXT);
+CREATE TABLE tiles (
+	zoom_level INTEGER NOT NULL,
+	tile_column INTEGER NOT NULL,
+	tile_row INTEGER NOT NULL,
+	tile_data BLOB NOT NULL
+);
+CREATE UNIQUE INDEX tile_index ON tiles (zoom_level, tile_column, tile_row);`
+
+// ExtractOptions defines the area and zoom range of an extract
+type ExtractOptions struct {
+	// Bounds are the WGS84 bounds in the order west, south, east, north
+	Bounds [4]float64
+
+	MinZoom, MaxZoom uint8
+
+	// MaxSize limits the size of the tile data in bytes, zero is unlimited
+	MaxSize int64
+}
+
+// ParseBounds parses comma separated WGS84 bounds in the order west, south,
+// east, north
+func ParseBounds(s string) ([4]float64, error) {
+	var b [4]float64
+
+	values := strings.Split(s, ",")
+	if len(values) != 4 {
+		return b, errors.New("bounds must have four values")
+	}
+
+	for i, v := range values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) {
+			return b, fmt.Errorf("invalid bounds value %q", v)
+		}
+		b[i] = f
+	}
+
+	if b[0] < -180 || b[2] > 180 || b[0] >= b[2] {
+		return b, errors.New("longitudes must be ascending between -180 and 180")
+	}
+	if b[1] < -90 || b[3] > 90 || b[1] >= b[3] {
+		return b, errors.New("latitudes must be ascending between -90 and 90")
+	}
+
+	return b, nil
+}
+
+// Extract writes the tiles within the bounds and zoom range into a new
+// MBTiles file. The metadata is copied with adjusted bounds, center and zoom
+// range. UTF grids are not included. If the tile data exceeds the size limit,
+// ErrExtractTooLarge is returned and the file is incomplete.
+func (ts *Tileset) Extract(file string, opts ExtractOptions) (err error) {
+	if opts.MinZoom > opts.MaxZoom || opts.MaxZoom > tms.MaxZoom {
+		return fmt.Errorf("invalid zoom range %v to %v", opts.MinZoom, opts.MaxZoom)
+	}
+
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := db.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if _, err = db.Exec(extractSchema); err != nil {
+		return fmt.Errorf("could not create extract: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = ts.extractMetadata(tx, opts); err != nil {
+		return fmt.Errorf("could not copy metadata: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO tiles (zoom_level, tile_column, tile_row, tile_data) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var size int64
+	for z := int(opts.MinZoom); z <= int(opts.MaxZoom); z++ {
+		if err = ts.extractZoom(stmt, uint8(z), opts, &size); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// extractZoom copies the tiles of the zoom level within the bounds and adds
+// the size of their data to size
+func (ts *Tileset) extractZoom(stmt *sql.Stmt, z uint8, opts ExtractOptions, size *int64) error {
+	// tiles that only touch the east or south edge are excluded
+	b := opts.Bounds
+	minX, minY := tms.LonLatToTile(b[0], b[3], z)
+	px, py := tms.LonLatToPixel(b[2], b[1], z)
+	maxX := uint64(math.Max(float64(minX), math.Ceil(px/tms.TileSize)-1))
+	maxY := uint64(math.Max(float64(minY), math.Ceil(py/tms.TileSize)-1))
+
+	// the rows are counted from the bottom
+	last := (uint64(1) << z) - 1
+	minRow, maxRow := last-maxY, last-minY
+
+	rows, err := ts.database.Query("SELECT tile_column, tile_row, tile_data FROM tiles "+
+		"WHERE zoom_level = ? AND tile_column BETWEEN ? AND ? AND tile_row BETWEEN ? AND ?", z, minX, maxX, minRow, maxRow)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var x, y uint64
+		var data []byte
+		if err := rows.Scan(&x, &y, &data); err != nil {
+			return err
+		}
+
+		if *size += int64(len(data)); opts.MaxSize > 0 && *size > opts.MaxSize {
+			return ErrExtractTooLarge
+		}
+
+		if _, err := stmt.Exec(z, x, y, data); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// extractMetadata copies the metadata and replaces the bounds, center and
+// zoom range by the ones of the extract
+func (ts *Tileset) extractMetadata(tx *sql.Tx, opts ExtractOptions) error {
+	rows, err := ts.database.Query("SELECT name, value FROM metadata")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	md := make(map[string]string)
+	var names []string
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		if _, ok := md[name]; !ok {
+			names = append(names, name)
+		}
+		md[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	b := opts.Bounds
+	center := [3]float64{(b[0] + b[2]) / 2, (b[1] + b[3]) / 2, float64(opts.MinZoom)}
+	if c, err := stringToCenter(md["center"]); err == nil && c[0] >= b[0] && c[0] <= b[2] && c[1] >= b[1] && c[1] <= b[3] {
+		center[0], center[1] = c[0], c[1]
+		center[2] = math.Max(float64(opts.MinZoom), math.Min(float64(opts.MaxZoom), c[2]))
+	}
+
+	adjusted := map[string]string{
+		"bounds":  fmt.Sprintf("%v,%v,%v,%v", b[0], b[1], b[2], b[3]),
+		"center":  fmt.Sprintf("%v,%v,%v", center[0], center[1], center[2]),
+		"minzoom": strconv.Itoa(int(opts.MinZoom)),
+		"maxzoom": strconv.Itoa(int(opts.MaxZoom)),
+	}
+	for _, name := range []string{"bounds", "center", "minzoom", "maxzoom"} {
+		if _, ok := md[name]; !ok {
+			names = append(names, name)
+		}
+		md[name] = adjusted[name]
+	}
+
+	for _, name := range names {
+		if _, err := tx.Exec("INSERT INTO metadata (name, value) VALUES (?, ?)", name, md[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package mbtiles
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseBounds(t *testing.T) {
+	tests := []struct {
+		s    string
+		want [4]float64
+		ok   bool
+	}{
+		{"-180,-85,180,85", [4]float64{-180, -85, 180, 85}, true},
+		{" -10.5, 0 ,10.25,20 ", [4]float64{-10.5, 0, 10.25, 20}, true},
+		{"-10,0,10", [4]float64{}, false},
+		{"-10,0,10,20,30", [4]float64{}, false},
+		{"a,0,10,20", [4]float64{}, false},
+		{"NaN,0,10,20", [4]float64{}, false},
+		{"10,0,-10,20", [4]float64{}, false},
+		{"-190,0,10,20", [4]float64{}, false},
+		{"-10,20,10,0", [4]float64{}, false},
+		{"-10,0,10,95", [4]float64{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseBounds(tt.s)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ParseBounds() error = %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && got != tt.want {
+				t.Errorf("ParseBounds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	ts, err := NewTileset(tilesFixture(t, 2))
+	if err != nil {
+		t.Fatalf("NewTileset() error = %v", err)
+	}
+	defer ts.Close()
+
+	tests := []struct {
+		name string
+		opts ExtractOptions
+		// want are the TMS coordinates of the extracted tiles
+		want []TileCoord
+		err  error
+	}{
+		{
+			name: "north west quadrant",
+			opts: ExtractOptions{Bounds: [4]float64{-180, 0, 0, 85}, MinZoom: 1, MaxZoom: 2},
+			want: []TileCoord{
+				{Z: 1, X: 0, Y: 1},
+				{Z: 2, X: 0, Y: 2}, {Z: 2, X: 0, Y: 3}, {Z: 2, X: 1, Y: 2}, {Z: 2, X: 1, Y: 3},
+			},
+		},
+		{
+			name: "center",
+			opts: ExtractOptions{Bounds: [4]float64{-10, -10, 10, 10}, MinZoom: 0, MaxZoom: 2},
+			want: []TileCoord{
+				{Z: 0, X: 0, Y: 0},
+				{Z: 1, X: 0, Y: 0}, {Z: 1, X: 0, Y: 1}, {Z: 1, X: 1, Y: 0}, {Z: 1, X: 1, Y: 1},
+				{Z: 2, X: 1, Y: 1}, {Z: 2, X: 1, Y: 2}, {Z: 2, X: 2, Y: 1}, {Z: 2, X: 2, Y: 2},
+			},
+		},
+		{
+			name: "south east tile",
+			opts: ExtractOptions{Bounds: [4]float64{100, -80, 170, -70}, MinZoom: 2, MaxZoom: 2},
+			want: []TileCoord{{Z: 2, X: 3, Y: 0}},
+		},
+		{
+			name: "size limit",
+			opts: ExtractOptions{Bounds: [4]float64{-180, -85, 180, 85}, MinZoom: 0, MaxZoom: 2, MaxSize: 100},
+			err:  ErrExtractTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "extract.mbtiles")
+
+			err := ts.Extract(file, tt.opts)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Extract() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			db, err := sql.Open("sqlite3", file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			rows, err := db.Query("SELECT zoom_level, tile_column, tile_row, tile_data FROM tiles " +
+				"ORDER BY zoom_level, tile_column, tile_row")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var got []TileCoord
+			for rows.Next() {
+				var tc TileCoord
+				var data []byte
+				if err := rows.Scan(&tc.Z, &tc.X, &tc.Y, &data); err != nil {
+					t.Fatal(err)
+				}
+				if want := fmt.Sprintf("%s%d/%d/%d", pngHeader, tc.Z, tc.X, tc.Y); string(data) != want {
+					t.Errorf("tile %v has the data of %q", tc, data[len(pngHeader):])
+				}
+				got = append(got, tc)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tiles = %v, want %v", got, tt.want)
+			}
+
+			var minZoom, maxZoom string
+			if err := db.QueryRow("SELECT (SELECT value FROM metadata WHERE name = 'minzoom'), "+
+				"(SELECT value FROM metadata WHERE name = 'maxzoom')").Scan(&minZoom, &maxZoom); err != nil {
+				t.Fatal(err)
+			}
+			if minZoom != fmt.Sprint(tt.opts.MinZoom) || maxZoom != fmt.Sprint(tt.opts.MaxZoom) {
+				t.Errorf("zoom range metadata = %v-%v, want %v-%v", minZoom, maxZoom, tt.opts.MinZoom, tt.opts.MaxZoom)
+			}
+		})
+	}
+
+	if err := ts.Extract(filepath.Join(t.TempDir(), "x.mbtiles"), ExtractOptions{MinZoom: 3, MaxZoom: 2}); err == nil {
+		t.Error("Extract() of an invalid zoom range error = nil")
+	}
+}
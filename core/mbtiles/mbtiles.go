@@ -3,21 +3,22 @@ package mbtiles
 
 import (
 	"bytes"
-	"compress/gzip"
 	"compress/zlib"
+	"context"
 	"database/sql"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/logger"
+	"github.com/tarkov-database/tileserver/core/compression"
+
 	_ "github.com/mattn/go-sqlite3" // import sqlite3 driver
 )
 
@@ -29,64 +30,11 @@ var (
 	ErrTileNotFound             = errors.New("tile not found")
 	ErrNoUTFGrid                = errors.New("tileset does not contain UTF grids")
 	ErrInvalidTileCoord         = errors.New("tile coordinates are not valid")
+	ErrUnknownCompression       = errors.New("unknown tile compression")
 )
 
-const fileExtension = ".mbtiles"
-
-var tilesets = map[string]*Tileset{}
-
-// LoadTilesets creates a Tileset of all MBTiles in the specified directory
-// and adds them to the internal map
-func LoadTilesets(path string) error {
-	files, err := os.ReadDir(path)
-	if err != nil {
-		return fmt.Errorf("reading tileset directory failed: %w", err)
-	}
-
-	ch := make(chan *Tileset, 1)
-	wg := &sync.WaitGroup{}
-
-	for _, f := range files {
-		name := f.Name()
-		if !f.IsDir() && filepath.Ext(f.Name()) == fileExtension {
-			wg.Add(1)
-			go func(fn string) {
-				ts, err := NewTileset(fmt.Sprintf("%s/%s", path, fn))
-				if err != nil {
-					logger.Errorf("Loading tileset \"%s\" failed: %s", fn, err)
-				}
-				ch <- ts
-				wg.Done()
-			}(name)
-		}
-	}
-
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
-
-	for ts := range ch {
-		if ts != nil {
-			tilesets[strings.TrimSuffix(ts.Filename, fileExtension)] = ts
-		} else {
-			err = fmt.Errorf("some tilesets could not be loaded")
-		}
-	}
-
-	logger.Infof("%v tileset(s) loaded successfully", len(tilesets))
-
-	return err
-}
-
-// GetTileset returns a Tileset by the given ID
-func GetTileset(id string) (*Tileset, error) {
-	if ts, ok := tilesets[id]; ok {
-		return ts, nil
-	}
-
-	return nil, ErrTilesetNotFound
-}
+// FileExtension is the file extension of MBTiles files
+const FileExtension = ".mbtiles"
 
 // TileFormat represents the format of a tile
 type TileFormat int
@@ -99,6 +47,7 @@ const (
 	WEBP
 	GZIP
 	ZLIB
+	JSON
 )
 
 var formatStrings = [...]string{
@@ -107,8 +56,9 @@ var formatStrings = [...]string{
 	"png",
 	"jpg",
 	"webp",
-	"gzib",
+	"gzip",
 	"zlib",
+	"json",
 }
 
 // String returns a string representing the TileFormat
@@ -145,15 +95,53 @@ func (f TileFormat) ContentType() string {
 	case JPG:
 		return "image/jpeg"
 	case PBF:
-		return "application/x-protobuf" // Content-Encoding header must be gzip
+		return "application/x-protobuf" // Content-Encoding header must match the compression
 	case WEBP:
 		return "image/webp"
+	case JSON:
+		return "application/json"
 	default:
 		return ""
 	}
 }
 
+// Encoding returns the content encoding of the compressed formats GZIP and
+// ZLIB and compression.Identity for all others
+func (f TileFormat) Encoding() compression.Encoding {
+	switch f {
+	case GZIP:
+		return compression.Gzip
+	case ZLIB:
+		return compression.Deflate
+	default:
+		return compression.Identity
+	}
+}
+
+// IsRaster reports whether the TileFormat is an image format
+func (f TileFormat) IsRaster() bool {
+	switch f {
+	case PNG, JPG, WEBP:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseTileFormat returns the TileFormat represented by the string
+func ParseTileFormat(s string) (TileFormat, error) {
+	if f := stringToTileFormat(s); f != UNKNOWN {
+		return f, nil
+	}
+
+	return UNKNOWN, ErrInvalidTileFormat
+}
+
 func stringToTileFormat(s string) TileFormat {
+	if s == "jpeg" {
+		return JPG // not conforming to the spec, but commonly used
+	}
+
 	for i, k := range formatStrings {
 		if k == s {
 			return TileFormat(i)
@@ -216,15 +204,20 @@ func stringToLayerType(s string) LayerType {
 type Tileset struct {
 	Filename           string
 	Format             TileFormat
+	Compression        compression.Encoding
 	Timestamp          time.Time
 	UTFGrid            bool
 	UTFGridCompression TileFormat
 
+	path     string
+	modified time.Time
+	size     int64
 	database *sql.DB
+	etags    atomic.Pointer[sql.DB]
 }
 
 // NewTileset creates a new Tileset by the given MBTiles file
-func NewTileset(file string) (*Tileset, error) {
+func NewTileset(file string) (ts *Tileset, err error) {
 	fileStat, err := os.Stat(file)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file stats for mbtiles file: %w", err)
@@ -234,6 +227,11 @@ func NewTileset(file string) (*Tileset, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			db.Close()
+		}
+	}()
 
 	// Validate the mbtiles file
 	// 'tiles', 'metadata' tables or views must be present
@@ -246,30 +244,73 @@ func NewTileset(file string) (*Tileset, error) {
 		return nil, fmt.Errorf("missing required table: 'tiles' OR 'metadata'")
 	}
 
-	// Query a sample tile to determine format
-	var data []byte
-	if err = db.QueryRow("SELECT tile_data FROM tiles LIMIT 1").Scan(&data); err != nil {
+	// The format and compression metadata values take precedence,
+	// the sample tile is only inspected for values that are missing
+	var mdFormat, mdCompression string
+	if err = db.QueryRow("SELECT "+
+		"coalesce((SELECT value FROM metadata WHERE name = 'format'), ''), "+
+		"coalesce((SELECT value FROM metadata WHERE name = 'compression'), '')").
+		Scan(&mdFormat, &mdCompression); err != nil {
 		return nil, err
 	}
 
-	format, err := detectTileFormat(data)
-	if err != nil {
-		return nil, err
+	format := stringToTileFormat(strings.ToLower(strings.TrimSpace(mdFormat)))
+
+	encoding := compression.Identity
+	if len(mdCompression) > 0 {
+		if encoding, err = parseCompression(mdCompression); err != nil {
+			return nil, err
+		}
+	}
+
+	if format == UNKNOWN || (format == PBF && len(mdCompression) == 0) {
+		// Query a sample tile to determine format
+		var data []byte
+		if err = db.QueryRow("SELECT tile_data FROM tiles LIMIT 1").Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var detected TileFormat
+		detected, err = detectTileFormat(data)
+		if errors.Is(err, ErrUnknownTileFormatPattern) && (format == PBF || isMVT(data)) {
+			detected, err = PBF, nil // raw protobuf has no distinct signature
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// GZIP and ZLIB mask PBF, which is the only expected type for compressed tiles
+		switch detected {
+		case GZIP:
+			format, encoding = PBF, compression.Gzip
+		case ZLIB:
+			format, encoding = PBF, compression.Deflate
+		case PBF:
+			format, encoding = PBF, compression.Identity
+		default:
+			if format == UNKNOWN {
+				format = detected
+			}
+		}
 	}
 
-	if format == GZIP {
-		format = PBF // GZIP masks PBF, which is only expected type for tiles in GZIP format
+	if format != PBF && !format.IsRaster() {
+		return nil, fmt.Errorf("the tile format \"%s\" is not supported", format)
 	}
 
-	if format != PBF {
-		return nil, fmt.Errorf("The tile format \"%s\" is currently not supported", format)
+	if format.IsRaster() && encoding != compression.Identity {
+		return nil, fmt.Errorf("the tile format \"%s\" must not be compressed", format)
 	}
 
-	ts := &Tileset{
-		Filename:  fileStat.Name(),
-		Format:    format,
-		Timestamp: fileStat.ModTime().Round(time.Second),
-		database:  db,
+	ts = &Tileset{
+		Filename:    fileStat.Name(),
+		Format:      format,
+		Compression: encoding,
+		Timestamp:   fileStat.ModTime().Round(time.Second),
+		path:        file,
+		modified:    fileStat.ModTime(),
+		size:        fileStat.Size(),
+		database:    db,
 	}
 
 	// UTFGrids
@@ -296,13 +337,16 @@ func NewTileset(file string) (*Tileset, error) {
 			}
 		} else {
 			ts.UTFGrid = true
-			ts.UTFGridCompression, err = detectTileFormat(gridData)
+			ts.UTFGridCompression, err = detectGridFormat(gridData)
 			if err != nil {
 				return nil, fmt.Errorf("could not determine UTF Grid compression type: %w", err)
 			}
 		}
 	}
 
+	// a missing or outdated ETag index is ignored
+	_ = ts.openETagIndex()
+
 	return ts, nil
 }
 
@@ -364,10 +408,23 @@ func ParseTileCoord(z, x, y string) (tc *TileCoord, err error) {
 	return
 }
 
+// queryObserver is called with the duration of every tile query
+var queryObserver func(time.Duration)
+
+// ObserveQueries sets a function that is called with the duration of every
+// tile query. It must be set before tiles are read.
+func ObserveQueries(f func(time.Duration)) {
+	queryObserver = f
+}
+
 // GetTile reads a tile with tile identifiers z, x, y into []byte.
 func (ts *Tileset) GetTile(tc *TileCoord) ([]byte, error) {
 	var data []byte
 
+	if queryObserver != nil {
+		defer func(start time.Time) { queryObserver(time.Since(start)) }(time.Now())
+	}
+
 	if err := ts.database.QueryRow("SELECT tile_data FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?", tc.Z, tc.X, tc.Y).
 		Scan(&data); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -379,82 +436,78 @@ func (ts *Tileset) GetTile(tc *TileCoord) ([]byte, error) {
 	return data, nil
 }
 
-// GetGrid reads a UTFGrid with identifiers z, x, y into []byte.
-// This merges in grid key data. The data is returned in the original compression encoding (zlib or gzip)
+// GetGrid reads the UTFGrid with identifiers z, x, y and merges its key data
+// into the "data" member. The grid is returned in the compression of the
+// tileset, see GridFormat.
 func (ts *Tileset) GetGrid(tc *TileCoord) ([]byte, error) {
-	var data []byte
-
 	if !ts.UTFGrid {
-		return data, ErrNoUTFGrid
+		return nil, ErrNoUTFGrid
 	}
 
+	var data []byte
 	if err := ts.database.QueryRow("SELECT grid FROM grids WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?", tc.Z, tc.X, tc.Y).
 		Scan(&data); err != nil {
-		return data, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTileNotFound
+		}
+		return nil, err
 	}
 
-	rows, err := ts.database.Query("SELECT key_name, key_json FROM grid_data WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?", tc.Z, tc.X, tc.Y)
+	keys, err := ts.gridKeys(tc)
 	if err != nil {
-		return data, fmt.Errorf("cannot fetch grid data: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	keydata := make(map[string]interface{})
-	var key string
-	var value []byte
+	if len(keys) == 0 {
+		return data, nil // there is no key data for this grid
+	}
 
-	for rows.Next() {
-		if err := rows.Scan(&key, &value); err != nil {
-			return data, fmt.Errorf("could not fetch grid data: %w", err)
-		}
+	enc := ts.UTFGridCompression.Encoding()
 
-		valuejson := make(map[string]interface{})
-		json.Unmarshal(value, &valuejson)
-		keydata[key] = valuejson
+	raw, err := compression.Decode(data, enc)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress grid: %w", err)
 	}
 
-	if len(keydata) == 0 {
-		return data, nil // there is no key data for this tile, return
+	var grid map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &grid); err != nil {
+		return nil, fmt.Errorf("could not decode grid: %w", err)
 	}
 
-	var zreader io.ReadCloser  // instance of zlib or gzip reader
-	var zwriter io.WriteCloser // instance of zlip or gzip writer
-	var buf bytes.Buffer
-
-	reader := bytes.NewReader(data)
-
-	switch ts.UTFGridCompression {
-	case ZLIB:
-		zreader, err = zlib.NewReader(reader)
-		zwriter = zlib.NewWriter(&buf)
-	case GZIP:
-		zreader, err = gzip.NewReader(reader)
-		zwriter = gzip.NewWriter(&buf)
-	default:
-		err = fmt.Errorf("unknown grid compression")
-	}
-	if err != nil {
-		return data, err
+	if grid["data"], err = json.Marshal(keys); err != nil {
+		return nil, err
 	}
 
-	var utfjson map[string]interface{}
-	if err = json.NewDecoder(zreader).Decode(&utfjson); err != nil {
-		return data, err
+	if raw, err = json.Marshal(grid); err != nil {
+		return nil, err
 	}
-	zreader.Close()
 
-	// splice the key data into the UTF json
-	utfjson["data"] = keydata
+	return compression.Encode(raw, enc)
+}
 
-	// now re-encode to original zip encoding
-	if err = json.NewEncoder(zwriter).Encode(utfjson); err != nil {
-		return data, err
+// gridKeys returns the key data of the UTFGrid with identifiers z, x, y
+func (ts *Tileset) gridKeys(tc *TileCoord) (map[string]json.RawMessage, error) {
+	rows, err := ts.database.Query("SELECT key_name, key_json FROM grid_data WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?", tc.Z, tc.X, tc.Y)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch grid data: %w", err)
 	}
-	zwriter.Close()
+	defer rows.Close()
 
-	buf.Write(data)
+	keys := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("could not fetch grid data: %w", err)
+		}
 
-	return data, nil
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("invalid grid data of key %q", key)
+		}
+		keys[key] = value
+	}
+
+	return keys, rows.Err()
 }
 
 // GetMetadata reads the metadata table into Metadata, casting their values into
@@ -489,6 +542,11 @@ func (ts *Tileset) GetMetadata() (*Metadata, error) {
 			md.MinZoom, err = strconv.Atoi(value)
 		case "maxzoom":
 			md.MaxZoom, err = strconv.Atoi(value)
+		case "fillzoom":
+			var fillZoom int
+			if fillZoom, err = strconv.Atoi(value); err == nil {
+				md.FillZoom = &fillZoom
+			}
 		case "center":
 			md.Center, err = stringToCenter(value)
 		case "bounds":
@@ -524,13 +582,52 @@ func (ts *Tileset) GetMetadata() (*Metadata, error) {
 	return md, nil
 }
 
+// TileFormat returns the TileFormat of the Tileset
+func (ts *Tileset) TileFormat() TileFormat {
+	return ts.Format
+}
+
+// TileCompression returns the compression of the tiles of the Tileset
+func (ts *Tileset) TileCompression() compression.Encoding {
+	return ts.Compression
+}
+
+// GridFormat returns the compression of the UTF grids of the Tileset, JSON
+// for uncompressed grids or UNKNOWN if there are no grids
+func (ts *Tileset) GridFormat() TileFormat {
+	if !ts.UTFGrid {
+		return UNKNOWN
+	}
+
+	return ts.UTFGridCompression
+}
+
+// ModTime returns the modification time of the MBTiles file
+func (ts *Tileset) ModTime() time.Time {
+	return ts.Timestamp
+}
+
 // ContentType returns the content-type string of the TileFormat of the Tileset.
 func (ts *Tileset) ContentType() string {
 	return ts.Format.ContentType()
 }
 
-// Close closes the database connection of the Tileset
+// Ping checks that the database of the Tileset can be queried
+func (ts *Tileset) Ping(ctx context.Context) error {
+	var n int
+	if err := ts.database.QueryRowContext(ctx, "SELECT count(*) FROM (SELECT 1 FROM tiles LIMIT 1)").Scan(&n); err != nil {
+		return fmt.Errorf("could not query tiles: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the database connections of the Tileset
 func (ts *Tileset) Close() error {
+	if db := ts.etags.Swap(nil); db != nil {
+		db.Close()
+	}
+
 	return ts.database.Close()
 }
 
@@ -541,6 +638,7 @@ type Metadata struct {
 	Center      [3]float64 `json:"center,omitempty"`
 	MinZoom     int        `json:"minzoom,omitempty"`
 	MaxZoom     int        `json:"maxzoom,omitempty"`
+	FillZoom    *int       `json:"fillzoom,omitempty"`
 	Description string     `json:"description,omitempty"`
 	Version     string     `json:"version,omitempty"`
 	Type        LayerType  `json:"type,omitempty"`
@@ -583,16 +681,29 @@ type Attribute struct {
 
 var tileFomatPatterns = map[TileFormat][]byte{
 	GZIP: []byte("\x1f\x8b"), // this masks PBF format too
-	ZLIB: []byte("\x78\x9c"),
 	PNG:  []byte("\x89\x50\x4E\x47\x0D\x0A\x1A\x0A"),
 	JPG:  []byte("\xFF\xD8\xFF"),
-	WEBP: []byte("\x52\x49\x46\x46\xc0\x00\x00\x00\x57\x45\x42\x50\x56\x50"),
 }
 
+// WEBP files are RIFF containers, the four bytes between the RIFF and WEBP
+// markers hold the file size and therefore differ for every tile
+var (
+	webpRIFFPattern = []byte("RIFF")
+	webpPattern     = []byte("WEBP")
+)
+
 // detectFileFormat inspects the first few bytes of byte array to determine tile
 // format PBF tile format does not have a distinct signature, it will be
 // returned as GZIP, and it is up to caller to determine that it is a PBF format
 func detectTileFormat(data []byte) (TileFormat, error) {
+	if len(data) >= 12 && bytes.HasPrefix(data, webpRIFFPattern) && bytes.Equal(data[8:12], webpPattern) {
+		return WEBP, nil
+	}
+
+	if isZlib(data) {
+		return ZLIB, nil
+	}
+
 	for format, pattern := range tileFomatPatterns {
 		if bytes.HasPrefix(data, pattern) {
 			return format, nil
@@ -602,6 +713,89 @@ func detectTileFormat(data []byte) (TileFormat, error) {
 	return UNKNOWN, ErrUnknownTileFormatPattern
 }
 
+// isZlib reports whether the data starts with a zlib header (RFC 1950), which
+// is a deflate method byte with a 32K window followed by a flag byte, so that
+// the header is a multiple of 31. The flag byte differs by compression level.
+// The header is only two bytes, so the first deflate block has to be readable
+// as well and a preset dictionary is not supported.
+func isZlib(data []byte) bool {
+	if len(data) < 2 || data[0] != 0x78 || (uint16(data[0])<<8|uint16(data[1]))%31 != 0 || data[1]&0x20 != 0 {
+		return false
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+
+	_, err = r.Read(make([]byte, 1))
+
+	return err == nil || err == io.EOF
+}
+
+// detectGridFormat returns the compression GZIP or ZLIB of the UTFGrid or
+// JSON if it is not compressed
+func detectGridFormat(data []byte) (TileFormat, error) {
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		return JSON, nil
+	}
+
+	f, err := detectTileFormat(data)
+	if err != nil {
+		return UNKNOWN, err
+	}
+	if f != GZIP && f != ZLIB {
+		return UNKNOWN, ErrUnknownTileFormatPattern
+	}
+
+	return f, nil
+}
+
+// parseCompression returns the encoding of the compression metadata value
+func parseCompression(s string) (compression.Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none", "identity":
+		return compression.Identity, nil
+	case "gzip":
+		return compression.Gzip, nil
+	case "zlib", "deflate":
+		return compression.Deflate, nil
+	case "br", "brotli":
+		return compression.Brotli, nil
+	case "zstd":
+		return compression.Zstd, nil
+	default:
+		return compression.Identity, fmt.Errorf("%w: %q", ErrUnknownCompression, s)
+	}
+}
+
+// isMVT reports whether the data looks like an uncompressed vector tile.
+// A tile only consists of layers (field 3), which start with one of the
+// layer fields name, features, keys, values, extent or version. The key of
+// the version field (15) is 0x78 and not related to the zlib header.
+func isMVT(data []byte) bool {
+	if len(data) == 0 {
+		return true // an empty tile is a valid tile without layers
+	}
+
+	if data[0] != 0x1a {
+		return false
+	}
+
+	length, n := binary.Uvarint(data[1:])
+	if n <= 0 || length == 0 || length > uint64(len(data)-1-n) {
+		return false
+	}
+
+	switch data[1+n] {
+	case 0x0a, 0x12, 0x1a, 0x22, 0x28, 0x78:
+		return true
+	default:
+		return false
+	}
+}
+
 func stringToBounds(str string) (bounds [4]float64, err error) {
 	for i, v := range strings.Split(str, ",") {
 		bounds[i], err = strconv.ParseFloat(strings.TrimSpace(v), 64)
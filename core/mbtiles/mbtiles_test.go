@@ -0,0 +1,281 @@
+package mbtiles
+
+import (
+	"bytes"
+	"compress/zlib"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+)
+
+func TestDetectTileFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want TileFormat
+		err  error
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), PNG, nil},
+		{"jpg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), JPG, nil},
+		{"gzip", []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00"), GZIP, nil},
+		{"webp", []byte("RIFF\xc0\x00\x00\x00WEBPVP8 "), WEBP, nil},
+		{"webp other size", []byte("RIFF\x24\x1a\x00\x00WEBPVP8L"), WEBP, nil},
+		{"riff without webp", []byte("RIFF\x24\x1a\x00\x00WAVEfmt "), UNKNOWN, ErrUnknownTileFormatPattern},
+		{"short riff", []byte("RIFF\x24\x1a"), UNKNOWN, ErrUnknownTileFormatPattern},
+		{"empty", nil, UNKNOWN, ErrUnknownTileFormatPattern},
+		{"text", []byte("hello"), UNKNOWN, ErrUnknownTileFormatPattern},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectTileFormat(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("format = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// rawMVT is an uncompressed vector tile with an empty layer "roads" of
+// version 2 and extent 4096
+var rawMVT = []byte("\x1a\x0c\x78\x02\x0a\x05roads\x28\x80\x20")
+
+func TestIsZlib(t *testing.T) {
+	for level := zlib.BestSpeed; level <= zlib.BestCompression; level++ {
+		var buf bytes.Buffer
+		w, err := zlib.NewWriterLevel(&buf, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(rawMVT)
+		w.Close()
+
+		if !isZlib(buf.Bytes()) {
+			t.Errorf("isZlib() of level %v = false, want true", level)
+		}
+		if f, err := detectTileFormat(buf.Bytes()); err != nil || f != ZLIB {
+			t.Errorf("detectTileFormat() of level %v = %q, %v, want %q", level, f, err, ZLIB)
+		}
+	}
+
+	gzipped, err := compression.Encode(rawMVT, compression.Gzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"raw mvt", rawMVT},
+		{"gzip", gzipped},
+		{"empty", nil},
+		{"header only", []byte{0x78, 0x9c}},
+		{"invalid checksum", []byte{0x78, 0x9d, 0x03, 0x00}},
+		{"preset dictionary", []byte{0x78, 0xbb, 0x00, 0x00, 0x00, 0x01, 0x03, 0x00}},
+		{"invalid deflate block", []byte{0x78, 0x9c, 0xff, 0xff, 0xff, 0xff}},
+		{"text", []byte("x^hello")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if isZlib(tt.data) {
+				t.Error("isZlib() = true, want false")
+			}
+		})
+	}
+}
+
+func TestIsMVT(t *testing.T) {
+	gzipped, err := compression.Encode(rawMVT, compression.Gzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zlibbed, err := compression.Encode(rawMVT, compression.Deflate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"raw mvt", rawMVT, true},
+		{"layer name first", []byte("\x1a\x07\x0a\x05roads"), true},
+		{"empty tile", nil, true},
+		{"gzip", gzipped, false},
+		{"zlib", zlibbed, false},
+		{"png", []byte("\x89PNG\r\n\x1a\n"), false},
+		{"layer longer than data", []byte("\x1a\x20\x78\x02"), false},
+		{"empty layer", []byte("\x1a\x00"), false},
+		{"unknown layer field", []byte("\x1a\x02\x08\x01"), false},
+		{"truncated length", []byte("\x1a\x80"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isMVT(tt.data); got != tt.want {
+				t.Errorf("isMVT() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTileFormatString(t *testing.T) {
+	tests := []struct {
+		format TileFormat
+		want   string
+	}{
+		{PBF, "pbf"},
+		{PNG, "png"},
+		{JPG, "jpg"},
+		{WEBP, "webp"},
+		{GZIP, "gzip"},
+		{ZLIB, "zlib"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+		if got := stringToTileFormat(tt.want); got != tt.format {
+			t.Errorf("stringToTileFormat(%q) = %q, want %q", tt.want, got, tt.format)
+		}
+	}
+}
+
+func TestStringToTileFormat(t *testing.T) {
+	tests := []struct {
+		s    string
+		want TileFormat
+	}{
+		{"jpeg", JPG},
+		{"jpg", JPG},
+		{"gzib", UNKNOWN},
+		{"", UNKNOWN},
+		{"tiff", UNKNOWN},
+	}
+
+	for _, tt := range tests {
+		if got := stringToTileFormat(tt.s); got != tt.want {
+			t.Errorf("stringToTileFormat(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+// gridSchema creates the tables and views of UTF grids by the convention of
+// MBTiles files created by TileMill
+const gridSchema = `
+CREATE TABLE metadata (name text, value text);
+CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob);
+CREATE TABLE grid_utfgrid (zoom_level integer, tile_column integer, tile_row integer, grid_utfgrid blob);
+CREATE TABLE keymap (key_name text, key_json text);
+CREATE TABLE grid_key (zoom_level integer, tile_column integer, tile_row integer, key_name text);
+CREATE VIEW grids AS
+	SELECT zoom_level, tile_column, tile_row, grid_utfgrid AS grid FROM grid_utfgrid;
+CREATE VIEW grid_data AS
+	SELECT grid_key.zoom_level, grid_key.tile_column, grid_key.tile_row, keymap.key_name, keymap.key_json
+	FROM grid_key JOIN keymap ON grid_key.key_name = keymap.key_name;
+INSERT INTO metadata VALUES ('name', 'grids'), ('format', 'png'), ('minzoom', '0'), ('maxzoom', '1');
+INSERT INTO tiles VALUES (0, 0, 0, x'89504e470d0a1a0a');
+INSERT INTO keymap VALUES ('1', '{"name":"Customs"}'), ('2', '{"name":"Woods"}');
+INSERT INTO grid_key VALUES (0, 0, 0, '1'), (0, 0, 0, '2');
+`
+
+// gridFixture creates an MBTiles file with UTF grids in the given compression
+// at 0/0/0 with key data and at 1/0/0 without
+func gridFixture(t *testing.T, enc compression.Encoding) string {
+	file := filepath.Join(t.TempDir(), "grids.mbtiles")
+
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(gridSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	grids := map[TileCoord]string{
+		{Z: 0, X: 0, Y: 0}: `{"grid":[" !#","! #"],"keys":["","1","2"]}`,
+		{Z: 1, X: 0, Y: 0}: `{"grid":[" !","! "],"keys":["","1"]}`,
+	}
+	for tc, grid := range grids {
+		data, err := compression.Encode([]byte(grid), enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec("INSERT INTO grid_utfgrid VALUES (?, ?, ?, ?)", tc.Z, tc.X, tc.Y, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return file
+}
+
+func TestGetGrid(t *testing.T) {
+	tests := []struct {
+		name   string
+		enc    compression.Encoding
+		format TileFormat
+	}{
+		{"gzip", compression.Gzip, GZIP},
+		{"zlib", compression.Deflate, ZLIB},
+		{"uncompressed", compression.Identity, JSON},
+	}
+
+	golden := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.TrimSpace(data)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := NewTileset(gridFixture(t, tt.enc))
+			if err != nil {
+				t.Fatalf("NewTileset() error = %v", err)
+			}
+			defer ts.Close()
+
+			if !ts.UTFGrid || ts.UTFGridCompression != tt.format {
+				t.Fatalf("UTFGrid = %v %q, want true %q", ts.UTFGrid, ts.UTFGridCompression, tt.format)
+			}
+
+			for tc, file := range map[TileCoord]string{
+				{Z: 0, X: 0, Y: 0}: "grid.json",
+				{Z: 1, X: 0, Y: 0}: "grid_nokeys.json",
+			} {
+				tc := tc
+				data, err := ts.GetGrid(&tc)
+				if err != nil {
+					t.Fatalf("GetGrid(%v) error = %v", tc, err)
+				}
+
+				raw, err := compression.Decode(data, tt.enc)
+				if err != nil {
+					t.Fatalf("grid %v is not in the compression of the tileset: %v", tc, err)
+				}
+				if want := golden(file); !bytes.Equal(raw, want) {
+					t.Errorf("GetGrid(%v) =\n%s\nwant\n%s", tc, raw, want)
+				}
+			}
+
+			if _, err := ts.GetGrid(&TileCoord{Z: 1, X: 1, Y: 1}); !errors.Is(err, ErrTileNotFound) {
+				t.Errorf("GetGrid() of a missing grid error = %v, want %v", err, ErrTileNotFound)
+			}
+		})
+	}
+}
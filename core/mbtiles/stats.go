@@ -0,0 +1,186 @@
+package mbtiles
+
+import "sort"
+
+const (
+	// largestTiles is the number of largest tiles reported by Stats
+	largestTiles = 10
+
+	// coverageMaxZoom is the highest zoom level of coverage cells, tiles of
+	// higher zoom levels are mapped to their ancestor at this zoom level
+	coverageMaxZoom = 10
+)
+
+// Stats describes the tiles of a Tileset. Rows are counted from the top like
+// in tile URLs.
+type Stats struct {
+	Tiles       int64        `json:"tiles"`
+	Size        int64        `json:"size"`
+	AverageSize float64      `json:"average_size"`
+	Largest     []TileSize   `json:"largest"`
+	Zooms       []*ZoomStats `json:"zooms"`
+}
+
+// TileSize is the size of the tile data of a tile
+type TileSize struct {
+	Z    uint8  `json:"z"`
+	X    uint64 `json:"x"`
+	Y    uint64 `json:"y"`
+	Size int64  `json:"size"`
+}
+
+// ZoomStats describes the tiles of a zoom level
+type ZoomStats struct {
+	Zoom        uint8   `json:"zoom"`
+	Tiles       int64   `json:"tiles"`
+	MinColumn   uint64  `json:"min_column"`
+	MaxColumn   uint64  `json:"max_column"`
+	MinRow      uint64  `json:"min_row"`
+	MaxRow      uint64  `json:"max_row"`
+	Size        int64   `json:"size"`
+	AverageSize float64 `json:"average_size"`
+
+	// Coverage holds the cells covered by tiles of the zoom level
+	Coverage *Coverage `json:"-"`
+}
+
+// Coverage is a bitmap of the tiles of a zoom level, at most at
+// coverageMaxZoom
+type Coverage struct {
+	Zoom uint8
+	bits []uint64
+}
+
+func newCoverage(z uint8) *Coverage {
+	if z > coverageMaxZoom {
+		z = coverageMaxZoom
+	}
+
+	n := uint64(1) << (2 * z)
+	return &Coverage{Zoom: z, bits: make([]uint64, (n+63)/64)}
+}
+
+// add marks the cell of the tile at the zoom level, the row is counted from
+// the top
+func (c *Coverage) add(z uint8, x, y uint64) {
+	d := z - c.Zoom
+	x, y = x>>d, y>>d
+
+	i := y<<c.Zoom | x
+	c.bits[i/64] |= 1 << (i % 64)
+}
+
+// Runs calls f for every run of consecutive covered cells of a row with the
+// first column x, the row y counted from the top and the number of cells
+func (c *Coverage) Runs(f func(x, y, n uint64)) {
+	size := uint64(1) << c.Zoom
+
+	for y := uint64(0); y < size; y++ {
+		var start, n uint64
+		for x := uint64(0); x < size; x++ {
+			i := y<<c.Zoom | x
+			if c.bits[i/64]&(1<<(i%64)) != 0 {
+				if n == 0 {
+					start = x
+				}
+				n++
+				continue
+			}
+
+			if n > 0 {
+				f(start, y, n)
+				n = 0
+			}
+		}
+
+		if n > 0 {
+			f(start, y, n)
+		}
+	}
+}
+
+// Stats reads all tiles and returns their counts, extents and sizes by zoom
+// level and the largest tiles
+func (ts *Tileset) Stats() (*Stats, error) {
+	rows, err := ts.database.Query("SELECT zoom_level, tile_column, tile_row, length(tile_data) FROM tiles")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := &Stats{Largest: make([]TileSize, 0, largestTiles+1)}
+	zooms := make(map[uint8]*ZoomStats)
+
+	for rows.Next() {
+		var z, x, y, size int64
+		if err := rows.Scan(&z, &x, &y, &size); err != nil {
+			return nil, err
+		}
+		if z < 0 || z > 30 || x < 0 || y < 0 || y >= 1<<z {
+			continue // out of range, reported by Validate
+		}
+
+		t := TileSize{Z: uint8(z), X: uint64(x), Y: uint64(1<<z - 1 - y), Size: size}
+
+		zs, ok := zooms[t.Z]
+		if !ok {
+			zs = &ZoomStats{
+				Zoom:      t.Z,
+				MinColumn: t.X,
+				MaxColumn: t.X,
+				MinRow:    t.Y,
+				MaxRow:    t.Y,
+				Coverage:  newCoverage(t.Z),
+			}
+			zooms[t.Z] = zs
+		}
+
+		zs.Tiles++
+		zs.Size += t.Size
+		if t.X < zs.MinColumn {
+			zs.MinColumn = t.X
+		}
+		if t.X > zs.MaxColumn {
+			zs.MaxColumn = t.X
+		}
+		if t.Y < zs.MinRow {
+			zs.MinRow = t.Y
+		}
+		if t.Y > zs.MaxRow {
+			zs.MaxRow = t.Y
+		}
+		if t.X < 1<<t.Z {
+			zs.Coverage.add(t.Z, t.X, t.Y)
+		}
+
+		s.Tiles++
+		s.Size += t.Size
+
+		// keep the largest tiles sorted in descending order
+		if len(s.Largest) < largestTiles || t.Size > s.Largest[len(s.Largest)-1].Size {
+			i := sort.Search(len(s.Largest), func(i int) bool { return s.Largest[i].Size < t.Size })
+			s.Largest = append(s.Largest, TileSize{})
+			copy(s.Largest[i+1:], s.Largest[i:])
+			s.Largest[i] = t
+			if len(s.Largest) > largestTiles {
+				s.Largest = s.Largest[:largestTiles]
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.Tiles > 0 {
+		s.AverageSize = float64(s.Size) / float64(s.Tiles)
+	}
+
+	s.Zooms = make([]*ZoomStats, 0, len(zooms))
+	for _, zs := range zooms {
+		zs.AverageSize = float64(zs.Size) / float64(zs.Tiles)
+		s.Zooms = append(s.Zooms, zs)
+	}
+	sort.Slice(s.Zooms, func(i, j int) bool { return s.Zooms[i].Zoom < s.Zooms[j].Zoom })
+
+	return s, nil
+}
@@ -0,0 +1,132 @@
+package mbtiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+// run is a run of covered cells reported by Coverage.Runs
+type run struct {
+	x, y, n uint64
+}
+
+func runs(c *Coverage) []run {
+	var got []run
+	c.Runs(func(x, y, n uint64) {
+		got = append(got, run{x, y, n})
+	})
+
+	return got
+}
+
+func TestCoverageRuns(t *testing.T) {
+	tests := []struct {
+		name  string
+		zoom  uint8
+		tiles [][3]uint64
+		want  []run
+	}{
+		{
+			name: "empty",
+			zoom: 2,
+		},
+		{
+			name:  "single cell",
+			zoom:  0,
+			tiles: [][3]uint64{{0, 0, 0}},
+			want:  []run{{0, 0, 1}},
+		},
+		{
+			name:  "runs of rows",
+			zoom:  2,
+			tiles: [][3]uint64{{2, 0, 0}, {2, 1, 0}, {2, 3, 0}, {2, 0, 3}, {2, 1, 3}, {2, 2, 3}, {2, 3, 3}},
+			want:  []run{{0, 0, 2}, {3, 0, 1}, {0, 3, 4}},
+		},
+		{
+			name:  "duplicate tiles",
+			zoom:  1,
+			tiles: [][3]uint64{{1, 1, 1}, {1, 1, 1}},
+			want:  []run{{1, 1, 1}},
+		},
+		{
+			// the cells of words of the bitmap are consecutive
+			name:  "run across words",
+			zoom:  4,
+			tiles: [][3]uint64{{4, 15, 3}, {4, 0, 4}, {4, 1, 4}},
+			want:  []run{{15, 3, 1}, {0, 4, 2}},
+		},
+		{
+			name:  "descendants beyond the coverage zoom",
+			zoom:  coverageMaxZoom + 2,
+			tiles: [][3]uint64{{coverageMaxZoom + 2, 4, 8}, {coverageMaxZoom + 2, 7, 11}, {coverageMaxZoom + 2, 8, 8}},
+			want:  []run{{1, 2, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCoverage(tt.zoom)
+			for _, tile := range tt.tiles {
+				c.add(uint8(tile[0]), tile[1], tile[2])
+			}
+
+			if got := runs(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Runs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if c := newCoverage(coverageMaxZoom + 2); c.Zoom != coverageMaxZoom {
+		t.Errorf("Zoom = %v, want %v", c.Zoom, coverageMaxZoom)
+	}
+}
+
+func TestStats(t *testing.T) {
+	// the tiles out of range are skipped
+	ts, err := NewTileset(tilesFixture(t, 2,
+		"INSERT INTO tiles VALUES (-1, 0, 0, x'89504e470d0a1a0a')",
+		"INSERT INTO tiles VALUES (300, 0, 0, x'89504e470d0a1a0a')",
+		"INSERT INTO tiles VALUES (2, -1, 0, x'89504e470d0a1a0a')",
+		"INSERT INTO tiles VALUES (2, 0, -1, x'89504e470d0a1a0a')",
+		"INSERT INTO tiles VALUES (2, 0, 4, x'89504e470d0a1a0a')"))
+	if err != nil {
+		t.Fatalf("NewTileset() error = %v", err)
+	}
+	defer ts.Close()
+
+	s, err := ts.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	if s.Tiles != 21 {
+		t.Errorf("Tiles = %v, want 21", s.Tiles)
+	}
+	if len(s.Largest) != largestTiles {
+		t.Errorf("%v largest tiles, want %v", len(s.Largest), largestTiles)
+	}
+	for i := 1; i < len(s.Largest); i++ {
+		if s.Largest[i].Size > s.Largest[i-1].Size {
+			t.Errorf("largest tiles are not in descending order: %v", s.Largest)
+			break
+		}
+	}
+
+	if len(s.Zooms) != 3 {
+		t.Fatalf("%v zoom levels, want 3", len(s.Zooms))
+	}
+	for i, zs := range s.Zooms {
+		n := uint64(1) << i
+		if zs.Zoom != uint8(i) || zs.Tiles != int64(n*n) || zs.MaxColumn != n-1 || zs.MaxRow != n-1 {
+			t.Errorf("zoom %v = %+v, want %v tiles up to column and row %v", i, zs, n*n, n-1)
+		}
+
+		want := make([]run, n)
+		for y := range want {
+			want[y] = run{0, uint64(y), n}
+		}
+		if got := runs(zs.Coverage); !reflect.DeepEqual(got, want) {
+			t.Errorf("zoom %v coverage = %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package mbtiles
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+)
+
+// maxReportedTiles is the number of invalid tiles reported by name per
+// problem, further tiles are only counted
+const maxReportedTiles = 10
+
+// TileCounts returns the number of tiles per zoom level, including invalid
+// zoom levels
+func (ts *Tileset) TileCounts() (map[int64]int64, error) {
+	rows, err := ts.database.Query("SELECT zoom_level, count(*) FROM tiles GROUP BY zoom_level")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int64)
+
+	var z, n int64
+	for rows.Next() {
+		if err := rows.Scan(&z, &n); err != nil {
+			return nil, err
+		}
+		counts[z] = n
+	}
+
+	return counts, rows.Err()
+}
+
+// Validate runs integrity checks of the database, the metadata and every tile
+// and returns the problems found
+func (ts *Tileset) Validate() []error {
+	var problems []error
+
+	rows, err := ts.database.Query("PRAGMA quick_check")
+	if err != nil {
+		return append(problems, fmt.Errorf("could not run integrity check: %w", err))
+	}
+	for rows.Next() {
+		var res string
+		if err := rows.Scan(&res); err != nil {
+			problems = append(problems, err)
+			break
+		}
+		if res != "ok" {
+			problems = append(problems, fmt.Errorf("database integrity: %s", res))
+		}
+	}
+	rows.Close()
+
+	problems = append(problems, ts.validateMetadata()...)
+	problems = append(problems, ts.validateTiles()...)
+
+	return problems
+}
+
+func (ts *Tileset) validateMetadata() []error {
+	var problems []error
+
+	var name, format int
+	if err := ts.database.QueryRow("SELECT "+
+		"(SELECT count(*) FROM metadata WHERE name = 'name' AND value != ''), "+
+		"(SELECT count(*) FROM metadata WHERE name = 'format' AND value != '')").
+		Scan(&name, &format); err != nil {
+		return append(problems, fmt.Errorf("could not read metadata: %w", err))
+	}
+	if name == 0 {
+		problems = append(problems, fmt.Errorf("metadata: required key \"name\" is missing"))
+	}
+	if format == 0 {
+		problems = append(problems, fmt.Errorf("metadata: required key \"format\" is missing"))
+	}
+
+	md, err := ts.GetMetadata()
+	if err != nil {
+		return append(problems, fmt.Errorf("metadata: %w", err))
+	}
+
+	if md.Format != UNKNOWN && md.Format != ts.Format {
+		problems = append(problems, fmt.Errorf("metadata: format %q does not match the tiles (%s)", md.Format, ts.Format))
+	}
+
+	if md.MinZoom < 0 || md.MaxZoom > 30 || md.MinZoom > md.MaxZoom {
+		problems = append(problems, fmt.Errorf("metadata: invalid zoom range %v to %v", md.MinZoom, md.MaxZoom))
+	}
+
+	b := md.Bounds
+	if b != [4]float64{} {
+		if b[0] < -180 || b[2] > 180 || b[1] < -90 || b[3] > 90 || b[0] >= b[2] || b[1] >= b[3] {
+			problems = append(problems, fmt.Errorf("metadata: invalid bounds %v", b))
+		} else if c := md.Center; c != [3]float64{} && (c[0] < b[0] || c[0] > b[2] || c[1] < b[1] || c[1] > b[3]) {
+			problems = append(problems, fmt.Errorf("metadata: center %v is outside of the bounds", c))
+		}
+	}
+
+	var minZoom, maxZoom sql.NullInt64
+	if err := ts.database.QueryRow("SELECT min(zoom_level), max(zoom_level) FROM tiles").Scan(&minZoom, &maxZoom); err != nil {
+		return append(problems, fmt.Errorf("could not read zoom levels: %w", err))
+	}
+	if minZoom.Valid && (int(minZoom.Int64) < md.MinZoom || int(maxZoom.Int64) > md.MaxZoom) {
+		problems = append(problems, fmt.Errorf("metadata: tiles exist from zoom %v to %v, outside of the zoom range %v to %v",
+			minZoom.Int64, maxZoom.Int64, md.MinZoom, md.MaxZoom))
+	}
+
+	return problems
+}
+
+// tileProblem counts the tiles with the same problem
+type tileProblem struct {
+	desc  string
+	count int
+	tiles []string
+}
+
+func (p *tileProblem) add(z, x, y int64) {
+	p.count++
+	if len(p.tiles) < maxReportedTiles {
+		p.tiles = append(p.tiles, fmt.Sprintf("%v/%v/%v", z, x, y))
+	}
+}
+
+func (p *tileProblem) err() error {
+	if p.count == 0 {
+		return nil
+	}
+
+	more := ""
+	if p.count > len(p.tiles) {
+		more = fmt.Sprintf(" and %v more", p.count-len(p.tiles))
+	}
+
+	return fmt.Errorf("%v tile(s) %s: %v%s", p.count, p.desc, p.tiles, more)
+}
+
+func (ts *Tileset) validateTiles() []error {
+	outOfRange := &tileProblem{desc: "with coordinates out of range"}
+	empty := &tileProblem{desc: "without data"}
+	mismatch := &tileProblem{desc: fmt.Sprintf("not in the format of the tileset (%s)", ts.Format)}
+
+	rows, err := ts.database.Query("SELECT zoom_level, tile_column, tile_row, tile_data FROM tiles")
+	if err != nil {
+		return []error{fmt.Errorf("could not read tiles: %w", err)}
+	}
+	defer rows.Close()
+
+	// the coordinates are scanned as signed integers, so that negative and
+	// too large values are reported instead of failing the scan
+	var z, x, y int64
+	var data []byte
+	for rows.Next() {
+		if err := rows.Scan(&z, &x, &y, &data); err != nil {
+			return []error{fmt.Errorf("could not read tiles: %w", err)}
+		}
+
+		switch {
+		case z < 0 || z > 30 || x < 0 || y < 0 || x >= 1<<z || y >= 1<<z:
+			outOfRange.add(z, x, y)
+		case len(data) == 0:
+			// an empty vector tile is a valid tile without layers
+			if ts.Format != PBF {
+				empty.add(z, x, y)
+			}
+		case !ts.matchesFormat(data):
+			mismatch.add(z, x, y)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return []error{fmt.Errorf("could not read tiles: %w", err)}
+	}
+
+	var problems []error
+	for _, p := range []*tileProblem{outOfRange, empty, mismatch} {
+		if err := p.err(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	return problems
+}
+
+// matchesFormat reports whether the tile data has the format and
+// compression of the tileset
+func (ts *Tileset) matchesFormat(data []byte) bool {
+	f, err := detectTileFormat(data)
+
+	if ts.Format != PBF {
+		return err == nil && f == ts.Format
+	}
+
+	switch ts.Compression {
+	case compression.Gzip:
+		return f == GZIP
+	case compression.Deflate:
+		return f == ZLIB
+	case compression.Identity:
+		return err != nil && isMVT(data)
+	default:
+		return true // not detectable by signature
+	}
+}
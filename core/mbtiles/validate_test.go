@@ -0,0 +1,168 @@
+package mbtiles
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pngHeader is the signature of PNG files, the fixture tiles only consist of
+// it and their coordinates
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+// tilesSchema has no constraints, so that invalid tiles can be inserted
+const tilesSchema = `CREATE TABLE metadata (name text, value text);
+CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob);`
+
+// tilesFixture creates an MBTiles file with all PNG tiles of the zoom levels
+// 0 to maxZoom and runs the statements on it
+func tilesFixture(t *testing.T, maxZoom uint8, stmts ...string) string {
+	file := filepath.Join(t.TempDir(), "tiles.mbtiles")
+
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(tilesSchema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO metadata VALUES ('name', 'tiles'), ('format', 'png'), "+
+		"('minzoom', '0'), ('maxzoom', ?), ('center', '-5,5,1')", fmt.Sprint(maxZoom)); err != nil {
+		t.Fatal(err)
+	}
+
+	for z := uint8(0); z <= maxZoom; z++ {
+		for x := uint64(0); x < 1<<z; x++ {
+			for y := uint64(0); y < 1<<z; y++ {
+				data := fmt.Sprintf("%s%d/%d/%d", pngHeader, z, x, y)
+				if _, err := db.Exec("INSERT INTO tiles VALUES (?, ?, ?, ?)", z, x, y, []byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return file
+}
+
+func TestTileCounts(t *testing.T) {
+	ts, err := NewTileset(tilesFixture(t, 2,
+		"INSERT INTO tiles VALUES (-1, 0, 0, x'89504e470d0a1a0a')",
+		"INSERT INTO tiles VALUES (300, 0, 0, x'89504e470d0a1a0a')"))
+	if err != nil {
+		t.Fatalf("NewTileset() error = %v", err)
+	}
+	defer ts.Close()
+
+	got, err := ts.TileCounts()
+	if err != nil {
+		t.Fatalf("TileCounts() error = %v", err)
+	}
+
+	if want := map[int64]int64{-1: 1, 0: 1, 1: 4, 2: 16, 300: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TileCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		stmts []string
+		want  []string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "missing name",
+			stmts: []string{
+				"DELETE FROM metadata WHERE name = 'name'",
+			},
+			want: []string{`required key "name" is missing`},
+		},
+		{
+			name: "invalid bounds",
+			stmts: []string{
+				"INSERT INTO metadata VALUES ('bounds', '10,0,-10,20')",
+			},
+			want: []string{"invalid bounds"},
+		},
+		{
+			name: "center outside of the bounds",
+			stmts: []string{
+				"INSERT INTO metadata VALUES ('bounds', '0,0,10,10')",
+			},
+			want: []string{"center [-5 5 1] is outside of the bounds"},
+		},
+		{
+			name: "tiles outside of the zoom range",
+			stmts: []string{
+				"UPDATE metadata SET value = '1' WHERE name = 'maxzoom'",
+			},
+			want: []string{"tiles exist from zoom 0 to 2"},
+		},
+		{
+			name: "coordinates out of range",
+			stmts: []string{
+				"INSERT INTO tiles VALUES (2, 4, 0, x'89504e470d0a1a0a')",
+				"INSERT INTO tiles VALUES (2, -1, 0, x'89504e470d0a1a0a')",
+				"INSERT INTO tiles VALUES (2, 0, -5, x'89504e470d0a1a0a')",
+				"INSERT INTO tiles VALUES (2, 0, 4294967296, x'89504e470d0a1a0a')",
+			},
+			want: []string{"4 tile(s) with coordinates out of range: [2/4/0 2/-1/0 2/0/-5 2/0/4294967296]"},
+		},
+		{
+			name: "zoom levels out of range",
+			stmts: []string{
+				"INSERT INTO tiles VALUES (-1, 0, 0, x'89504e470d0a1a0a')",
+				"INSERT INTO tiles VALUES (300, 0, 0, x'89504e470d0a1a0a')",
+			},
+			want: []string{
+				"tiles exist from zoom -1 to 300",
+				"2 tile(s) with coordinates out of range: [-1/0/0 300/0/0]",
+			},
+		},
+		{
+			name: "empty and mismatching tiles",
+			stmts: []string{
+				"UPDATE tiles SET tile_data = x'' WHERE zoom_level = 1 AND tile_row = 0",
+				"UPDATE tiles SET tile_data = x'ffd8ffe0' WHERE zoom_level = 2 AND tile_column = 3 AND tile_row = 3",
+			},
+			want: []string{
+				"2 tile(s) without data: [1/0/0 1/1/0]",
+				"1 tile(s) not in the format of the tileset (png): [2/3/3]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := NewTileset(tilesFixture(t, 2, tt.stmts...))
+			if err != nil {
+				t.Fatalf("NewTileset() error = %v", err)
+			}
+			defer ts.Close()
+
+			problems := ts.Validate()
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %v problem(s)", problems, len(tt.want))
+			}
+			for i, p := range problems {
+				if !strings.Contains(p.Error(), tt.want[i]) {
+					t.Errorf("problem %v = %q, want %q", i, p, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package mvt
+
+// bufferRatio is the size of the buffer around clipped tiles relative to the
+// layer extent
+const bufferRatio = 1.0 / 64
+
+// box is a clipping rectangle
+type box struct {
+	min, max float64
+}
+
+func (b box) contains(p Coord) bool {
+	return p.X >= b.min && p.X <= b.max && p.Y >= b.min && p.Y <= b.max
+}
+
+// Overzoom returns a tile for the descendant dz zoom levels below the tile
+// with the column x and row y relative to the top left of the tile. The
+// geometries are scaled and clipped to the descendant with a small buffer.
+// Features and layers without any remaining geometry are omitted.
+func Overzoom(t *Tile, dz uint8, x, y uint32) *Tile {
+	out := &Tile{Layers: make([]*Layer, 0, len(t.Layers))}
+
+	for _, l := range t.Layers {
+		extent := float64(l.Extent)
+		if extent == 0 {
+			extent = DefaultExtent
+		}
+
+		scale := float64(uint64(1) << dz)
+		ox, oy := float64(x)*extent, float64(y)*extent
+		b := box{min: -extent * bufferRatio, max: extent + extent*bufferRatio}
+
+		features := make([]*Feature, 0, len(l.Features))
+		for _, f := range l.Features {
+			parts, err := f.DecodeGeometry()
+			if err != nil {
+				continue
+			}
+
+			for _, part := range parts {
+				for i, p := range part {
+					part[i] = Coord{X: p.X*scale - ox, Y: p.Y*scale - oy}
+				}
+			}
+
+			switch f.Type {
+			case Point:
+				parts = clipPoints(parts, b)
+			case LineString:
+				parts = clipLines(parts, b)
+			case Polygon:
+				parts = clipPolygon(parts, b)
+			default:
+				parts = nil
+			}
+
+			if len(parts) == 0 {
+				continue
+			}
+
+			nf := *f
+			nf.EncodeGeometry(parts)
+			features = append(features, &nf)
+		}
+
+		if len(features) == 0 {
+			continue
+		}
+
+		nl := *l
+		nl.Features = features
+		out.Layers = append(out.Layers, &nl)
+	}
+
+	return out
+}
+
+func clipPoints(parts [][]Coord, b box) [][]Coord {
+	var out [][]Coord
+	for _, part := range parts {
+		for _, p := range part {
+			if b.contains(p) {
+				out = append(out, []Coord{p})
+			}
+		}
+	}
+
+	return out
+}
+
+func clipLines(parts [][]Coord, b box) [][]Coord {
+	var out [][]Coord
+	for _, part := range parts {
+		var line []Coord
+		flush := func() {
+			if len(line) > 1 {
+				out = append(out, line)
+			}
+			line = nil
+		}
+
+		for i := 1; i < len(part); i++ {
+			p, q := part[i-1], part[i]
+
+			cp, cq, ok := clipSegment(p, q, b)
+			if !ok {
+				flush()
+				continue
+			}
+
+			if len(line) == 0 {
+				line = append(line, cp)
+			}
+			line = append(line, cq)
+
+			// the segment leaves the box
+			if cq != q {
+				flush()
+			}
+		}
+		flush()
+	}
+
+	return out
+}
+
+// clipSegment clips the segment from p to q to the box with the
+// Liang-Barsky algorithm
+func clipSegment(p, q Coord, b box) (Coord, Coord, bool) {
+	dx, dy := q.X-p.X, q.Y-p.Y
+	t0, t1 := 0.0, 1.0
+
+	edges := [4][2]float64{
+		{-dx, p.X - b.min},
+		{dx, b.max - p.X},
+		{-dy, p.Y - b.min},
+		{dy, b.max - p.Y},
+	}
+
+	for _, e := range edges {
+		pe, qe := e[0], e[1]
+		if pe == 0 {
+			if qe < 0 {
+				return p, q, false
+			}
+			continue
+		}
+
+		r := qe / pe
+		if pe < 0 {
+			if r > t1 {
+				return p, q, false
+			}
+			if r > t0 {
+				t0 = r
+			}
+		} else {
+			if r < t0 {
+				return p, q, false
+			}
+			if r < t1 {
+				t1 = r
+			}
+		}
+	}
+
+	cp, cq := p, q
+	if t0 > 0 {
+		cp = Coord{X: p.X + t0*dx, Y: p.Y + t0*dy}
+	}
+	if t1 < 1 {
+		cq = Coord{X: p.X + t1*dx, Y: p.Y + t1*dy}
+	}
+
+	return cp, cq, true
+}
+
+// clipPolygon clips the rings of a (multi) polygon. Interior rings of an
+// exterior ring that is clipped away are dropped as well.
+func clipPolygon(rings [][]Coord, b box) [][]Coord {
+	var out [][]Coord
+	keep := false
+	for _, ring := range rings {
+		exterior := area(ring) > 0
+
+		clipped := clipRing(ring, b)
+		if len(clipped) < 3 || area(clipped) == 0 {
+			if exterior {
+				keep = false
+			}
+			continue
+		}
+
+		if exterior {
+			keep = true
+		} else if !keep {
+			continue
+		}
+
+		out = append(out, clipped)
+	}
+
+	return out
+}
+
+// clipRing clips the ring to the box with the Sutherland-Hodgman algorithm
+func clipRing(ring []Coord, b box) []Coord {
+	inside := [4]func(Coord) bool{
+		func(p Coord) bool { return p.X >= b.min },
+		func(p Coord) bool { return p.X <= b.max },
+		func(p Coord) bool { return p.Y >= b.min },
+		func(p Coord) bool { return p.Y <= b.max },
+	}
+	intersect := [4]func(p, q Coord) Coord{
+		func(p, q Coord) Coord { return atX(p, q, b.min) },
+		func(p, q Coord) Coord { return atX(p, q, b.max) },
+		func(p, q Coord) Coord { return atY(p, q, b.min) },
+		func(p, q Coord) Coord { return atY(p, q, b.max) },
+	}
+
+	out := ring
+	for i := range inside {
+		if len(out) == 0 {
+			break
+		}
+
+		in := out
+		out = make([]Coord, 0, len(in)+4)
+
+		prev := in[len(in)-1]
+		for _, p := range in {
+			switch {
+			case inside[i](p):
+				if !inside[i](prev) {
+					out = append(out, intersect[i](prev, p))
+				}
+				out = append(out, p)
+			case inside[i](prev):
+				out = append(out, intersect[i](prev, p))
+			}
+			prev = p
+		}
+	}
+
+	return out
+}
+
+func atX(p, q Coord, x float64) Coord {
+	return Coord{X: x, Y: p.Y + (q.Y-p.Y)*(x-p.X)/(q.X-p.X)}
+}
+
+func atY(p, q Coord, y float64) Coord {
+	return Coord{X: p.X + (q.X-p.X)*(y-p.Y)/(q.Y-p.Y), Y: y}
+}
+
+// area returns the signed area of the ring, which is positive for exterior
+// rings in tile coordinates
+func area(ring []Coord) float64 {
+	var sum float64
+	prev := ring[len(ring)-1]
+	for _, p := range ring {
+		sum += prev.X*p.Y - p.X*prev.Y
+		prev = p
+	}
+
+	return sum / 2
+}
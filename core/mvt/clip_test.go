@@ -0,0 +1,221 @@
+package mvt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClipSegment(t *testing.T) {
+	b := box{min: 0, max: 10}
+
+	tests := []struct {
+		name   string
+		p, q   Coord
+		cp, cq Coord
+		ok     bool
+	}{
+		{"inside", Coord{1, 1}, Coord{9, 9}, Coord{1, 1}, Coord{9, 9}, true},
+		{"enters", Coord{-5, 5}, Coord{5, 5}, Coord{0, 5}, Coord{5, 5}, true},
+		{"leaves", Coord{5, 5}, Coord{5, 15}, Coord{5, 5}, Coord{5, 10}, true},
+		{"crosses", Coord{-10, -10}, Coord{20, 20}, Coord{0, 0}, Coord{10, 10}, true},
+		{"diagonal", Coord{-5, 5}, Coord{5, -5}, Coord{0, 0}, Coord{0, 0}, true},
+		{"on edge", Coord{0, -5}, Coord{0, 15}, Coord{0, 0}, Coord{0, 10}, true},
+		{"outside", Coord{-5, -5}, Coord{-1, 20}, Coord{-5, -5}, Coord{-1, 20}, false},
+		{"parallel outside", Coord{-5, 11}, Coord{15, 11}, Coord{-5, 11}, Coord{15, 11}, false},
+		{"misses corner", Coord{-5, 4}, Coord{4, -5}, Coord{-5, 4}, Coord{4, -5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp, cq, ok := clipSegment(tt.p, tt.q, b)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if cp != tt.cp || cq != tt.cq {
+				t.Errorf("clipSegment() = %v %v, want %v %v", cp, cq, tt.cp, tt.cq)
+			}
+		})
+	}
+}
+
+func TestClipRing(t *testing.T) {
+	b := box{min: 0, max: 10}
+
+	tests := []struct {
+		name string
+		ring []Coord
+		want []Coord
+	}{
+		{
+			name: "inside",
+			ring: []Coord{{2, 2}, {8, 2}, {8, 8}, {2, 8}},
+			want: []Coord{{2, 2}, {8, 2}, {8, 8}, {2, 8}},
+		},
+		{
+			name: "overlaps right edge",
+			ring: []Coord{{5, 2}, {15, 2}, {15, 8}, {5, 8}},
+			want: []Coord{{5, 2}, {10, 2}, {10, 8}, {5, 8}},
+		},
+		{
+			name: "covers box",
+			ring: []Coord{{-5, -5}, {15, -5}, {15, 15}, {-5, 15}},
+			want: []Coord{{0, 10}, {0, 0}, {10, 0}, {10, 10}},
+		},
+		{
+			name: "triangle over corner",
+			ring: []Coord{{-4, 6}, {6, -4}, {6, 6}},
+			want: []Coord{{0, 6}, {0, 2}, {2, 0}, {6, 0}, {6, 6}},
+		},
+		{
+			name: "outside",
+			ring: []Coord{{20, 20}, {30, 20}, {30, 30}},
+			want: []Coord{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clipRing(tt.ring, b)
+			if !sameRing(got, tt.want) {
+				t.Errorf("clipRing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sameRing reports whether the rings have the same points in the same order,
+// regardless of the starting point
+func sameRing(a, b []Coord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) == 0 {
+		return true
+	}
+
+	for start := range a {
+		same := true
+		for i := range b {
+			if a[(start+i)%len(a)] != b[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestClipPolygon(t *testing.T) {
+	b := box{min: 0, max: 10}
+
+	exterior := []Coord{{-5, -5}, {5, -5}, {5, 5}, {-5, 5}}
+	hole := []Coord{{-2, -2}, {-2, 2}, {2, 2}, {2, -2}}
+	outside := []Coord{{20, 20}, {30, 20}, {30, 30}, {20, 30}}
+	outsideHole := []Coord{{22, 22}, {22, 28}, {28, 28}, {28, 22}}
+
+	if area(exterior) <= 0 || area(hole) >= 0 {
+		t.Fatal("test rings have the wrong winding")
+	}
+
+	got := clipPolygon([][]Coord{outside, outsideHole, exterior, hole}, b)
+	if len(got) != 2 {
+		t.Fatalf("clipPolygon() = %v, want the clipped exterior ring and its hole", got)
+	}
+
+	// the winding of the rings is kept after clipping
+	if area(got[0]) <= 0 {
+		t.Errorf("exterior ring %v has a non-positive area after clipping", got[0])
+	}
+	if area(got[1]) >= 0 {
+		t.Errorf("interior ring %v has a non-negative area after clipping", got[1])
+	}
+
+	if want := []Coord{{0, 0}, {5, 0}, {5, 5}, {0, 5}}; !sameRing(got[0], want) {
+		t.Errorf("exterior ring = %v, want %v", got[0], want)
+	}
+	if want := []Coord{{0, 0}, {0, 2}, {2, 2}, {2, 0}}; !sameRing(got[1], want) {
+		t.Errorf("interior ring = %v, want %v", got[1], want)
+	}
+}
+
+func TestOverzoom(t *testing.T) {
+	polygon := &Feature{ID: 1, HasID: true, Type: Polygon}
+	polygon.EncodeGeometry([][]Coord{{{1024, 1024}, {3072, 1024}, {3072, 3072}, {1024, 3072}}})
+
+	line := &Feature{ID: 2, HasID: true, Type: LineString}
+	line.EncodeGeometry([][]Coord{{{0, 1024}, {4096, 1024}}})
+
+	point := &Feature{ID: 3, HasID: true, Type: Point}
+	point.EncodeGeometry([][]Coord{{{3000, 3000}}})
+
+	tile := &Tile{Layers: []*Layer{
+		{Version: 2, Name: "shapes", Features: []*Feature{polygon, line, point}},
+		{Version: 2, Name: "empty", Extent: 4096},
+	}}
+
+	tests := []struct {
+		name string
+		x, y uint32
+		want map[uint64][][]Coord
+	}{
+		{
+			name: "top left",
+			x:    0, y: 0,
+			want: map[uint64][][]Coord{
+				1: {{{2048, 2048}, {4160, 2048}, {4160, 4160}, {2048, 4160}}},
+				2: {{{0, 2048}, {4160, 2048}}},
+			},
+		},
+		{
+			name: "bottom right",
+			x:    1, y: 1,
+			want: map[uint64][][]Coord{
+				1: {{{-64, -64}, {2048, -64}, {2048, 2048}, {-64, 2048}}},
+				3: {{{1904, 1904}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Overzoom(tile, 1, tt.x, tt.y)
+			if len(out.Layers) != 1 || out.Layers[0].Name != "shapes" {
+				t.Fatalf("Overzoom() layers = %v, want only the layer with features", out.Layers)
+			}
+
+			got := make(map[uint64][][]Coord)
+			for _, f := range out.Layers[0].Features {
+				parts, err := f.DecodeGeometry()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[f.ID] = parts
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Overzoom() features = %v, want %v", got, tt.want)
+			}
+			for id, want := range tt.want {
+				if len(got[id]) != len(want) {
+					t.Errorf("feature %v = %v, want %v", id, got[id], want)
+					continue
+				}
+				for i := range want {
+					if !sameRing(got[id][i], want[i]) {
+						t.Errorf("feature %v = %v, want %v", id, got[id], want)
+					}
+				}
+			}
+		})
+	}
+
+	// the geometries of the source tile are not modified
+	parts, _ := polygon.DecodeGeometry()
+	if want := []Coord{{1024, 1024}, {3072, 1024}, {3072, 3072}, {1024, 3072}}; !reflect.DeepEqual(parts[0], want) {
+		t.Errorf("source geometry = %v, want %v", parts[0], want)
+	}
+}
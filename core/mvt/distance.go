@@ -0,0 +1,71 @@
+package mvt
+
+import "math"
+
+// Distance returns the distance of the point to the geometry of the feature
+// in the coordinate space of the layer. It is zero for points inside of a
+// polygon.
+func (f *Feature) Distance(p Coord) (float64, error) {
+	parts, err := f.DecodeGeometry()
+	if err != nil {
+		return 0, err
+	}
+
+	d := math.Inf(1)
+	switch f.Type {
+	case Point:
+		for _, part := range parts {
+			for _, q := range part {
+				d = math.Min(d, math.Hypot(p.X-q.X, p.Y-q.Y))
+			}
+		}
+	case LineString:
+		for _, part := range parts {
+			for i := 1; i < len(part); i++ {
+				d = math.Min(d, segmentDistance(p, part[i-1], part[i]))
+			}
+		}
+	case Polygon:
+		if contains(parts, p) {
+			return 0, nil
+		}
+		for _, ring := range parts {
+			prev := ring[len(ring)-1]
+			for _, q := range ring {
+				d = math.Min(d, segmentDistance(p, prev, q))
+				prev = q
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// segmentDistance returns the distance of p to the segment from a to b
+func segmentDistance(p, a, b Coord) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/l))
+	}
+
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// contains reports whether the point is inside of the rings by the even-odd
+// rule, which respects holes
+func contains(rings [][]Coord, p Coord) bool {
+	inside := false
+	for _, ring := range rings {
+		prev := ring[len(ring)-1]
+		for _, q := range ring {
+			if (q.Y > p.Y) != (prev.Y > p.Y) && p.X < (prev.X-q.X)*(p.Y-q.Y)/(prev.Y-q.Y)+q.X {
+				inside = !inside
+			}
+			prev = q
+		}
+	}
+
+	return inside
+}
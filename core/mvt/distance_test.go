@@ -0,0 +1,84 @@
+package mvt
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	exterior := []Coord{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	hole := []Coord{{3, 3}, {3, 7}, {7, 7}, {7, 3}}
+	island := []Coord{{4, 4}, {6, 4}, {6, 6}, {4, 6}}
+
+	tests := []struct {
+		name  string
+		rings [][]Coord
+		p     Coord
+		want  bool
+	}{
+		{"inside", [][]Coord{exterior}, Coord{1, 1}, true},
+		{"outside", [][]Coord{exterior}, Coord{11, 5}, false},
+		{"left of the polygon", [][]Coord{exterior}, Coord{-1, 5}, false},
+		{"in the hole", [][]Coord{exterior, hole}, Coord{5, 5}, false},
+		{"between hole and exterior", [][]Coord{exterior, hole}, Coord{2, 5}, true},
+		{"island in the hole", [][]Coord{exterior, hole, island}, Coord{5, 5}, true},
+		{"hole of the island", [][]Coord{exterior, hole, island}, Coord{3.5, 5}, false},
+		{"concave notch", [][]Coord{{{0, 0}, {10, 0}, {10, 10}, {5, 5}, {0, 10}}}, Coord{5, 8}, false},
+		{"concave body", [][]Coord{{{0, 0}, {10, 0}, {10, 10}, {5, 5}, {0, 10}}}, Coord{5, 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contains(tt.rings, tt.p); got != tt.want {
+				t.Errorf("contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	polygon := &Feature{Type: Polygon}
+	polygon.EncodeGeometry([][]Coord{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+		{{3, 3}, {3, 7}, {7, 7}, {7, 3}},
+	})
+
+	line := &Feature{Type: LineString}
+	line.EncodeGeometry([][]Coord{{{0, 0}, {10, 0}, {10, 10}}})
+
+	points := &Feature{Type: Point}
+	points.EncodeGeometry([][]Coord{{{0, 0}}, {{6, 8}}})
+
+	tests := []struct {
+		name string
+		f    *Feature
+		p    Coord
+		want float64
+	}{
+		{"inside of the polygon", polygon, Coord{1, 1}, 0},
+		{"in the hole", polygon, Coord{5, 4}, 1},
+		{"outside of the polygon", polygon, Coord{13, 14}, 5},
+		{"on the line", line, Coord{5, 0}, 0},
+		{"beside the line", line, Coord{12, 5}, 2},
+		{"beyond the end of the line", line, Coord{-3, -4}, 5},
+		{"nearest point", points, Coord{6, 10}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.f.Distance(tt.p)
+			if err != nil {
+				t.Fatalf("Distance() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Distance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	invalid := &Feature{Type: Polygon, Geometry: []uint32{command(cmdMoveTo, 2), 0}}
+	if _, err := invalid.Distance(Coord{}); !errors.Is(err, ErrInvalidGeometry) {
+		t.Errorf("Distance() of an invalid geometry error = %v, want %v", err, ErrInvalidGeometry)
+	}
+}
@@ -0,0 +1,154 @@
+package mvt
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidGeometry = errors.New("invalid geometry")
+
+// geometry commands
+const (
+	cmdMoveTo    = 1
+	cmdLineTo    = 2
+	cmdClosePath = 7
+)
+
+// Coord is a position in the coordinate space of a layer
+type Coord struct {
+	X, Y float64
+}
+
+// DecodeGeometry returns the parts of the geometry of the feature. A part is a
+// single point of a (multi) point, a line of a (multi) line string or a ring
+// of a polygon. Rings are not closed by repeating the first point.
+func (f *Feature) DecodeGeometry() ([][]Coord, error) {
+	var parts [][]Coord
+	var part []Coord
+	var x, y int32
+
+	g := f.Geometry
+	for i := 0; i < len(g); {
+		cmd, count := g[i]&7, int(g[i]>>3)
+		i++
+
+		switch cmd {
+		case cmdMoveTo, cmdLineTo:
+			if i+2*count > len(g) {
+				return nil, ErrInvalidGeometry
+			}
+			for j := 0; j < count; j++ {
+				x += unzigzag(g[i])
+				y += unzigzag(g[i+1])
+				i += 2
+
+				if cmd == cmdMoveTo {
+					if len(part) > 0 {
+						parts = append(parts, part)
+					}
+					part = nil
+				}
+				part = append(part, Coord{float64(x), float64(y)})
+			}
+		case cmdClosePath:
+			if len(part) > 0 {
+				parts = append(parts, part)
+			}
+			part = nil
+		default:
+			return nil, ErrInvalidGeometry
+		}
+	}
+
+	if len(part) > 0 {
+		parts = append(parts, part)
+	}
+
+	return parts, nil
+}
+
+// EncodeGeometry sets the geometry of the feature from the parts, the
+// coordinates are rounded to integers
+func (f *Feature) EncodeGeometry(parts [][]Coord) {
+	g := make([]uint32, 0, len(f.Geometry))
+	var x, y int32
+
+	delta := func(p Coord) {
+		px, py := int32(math.Round(p.X)), int32(math.Round(p.Y))
+		g = append(g, zigzag(px-x), zigzag(py-y))
+		x, y = px, py
+	}
+
+	if f.Type == Point {
+		var n uint32
+		for _, part := range parts {
+			n += uint32(len(part))
+		}
+		if n == 0 {
+			f.Geometry = g
+			return
+		}
+
+		g = append(g, command(cmdMoveTo, n))
+		for _, part := range parts {
+			for _, p := range part {
+				delta(p)
+			}
+		}
+
+		f.Geometry = g
+		return
+	}
+
+	for _, part := range parts {
+		if len(part) < 2 {
+			continue
+		}
+
+		g = append(g, command(cmdMoveTo, 1))
+		delta(part[0])
+		g = append(g, command(cmdLineTo, uint32(len(part)-1)))
+		for _, p := range part[1:] {
+			delta(p)
+		}
+
+		if f.Type == Polygon {
+			g = append(g, command(cmdClosePath, 1))
+		}
+	}
+
+	f.Geometry = g
+}
+
+// Polygons groups the rings of a polygon geometry into polygons, each
+// starting with its exterior ring followed by its interior rings
+func Polygons(rings [][]Coord) [][][]Coord {
+	var polygons [][][]Coord
+	for _, ring := range rings {
+		if len(ring) < 3 {
+			continue
+		}
+
+		if area(ring) > 0 || len(polygons) == 0 {
+			polygons = append(polygons, [][]Coord{ring})
+			continue
+		}
+
+		last := len(polygons) - 1
+		polygons[last] = append(polygons[last], ring)
+	}
+
+	return polygons
+}
+
+func command(id, count uint32) uint32 {
+	return id&7 | count<<3
+}
+
+func zigzag(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
+
+func unzigzag(v uint32) int32 {
+	return int32(v>>1) ^ -int32(v&1)
+}
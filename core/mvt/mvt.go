@@ -0,0 +1,355 @@
+// Package mvt implements an encoder and decoder of Mapbox Vector Tiles as
+// described in https://github.com/mapbox/vector-tile-spec/tree/master/2.1
+package mvt
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultExtent is the extent of a layer without an extent field
+const DefaultExtent = 4096
+
+// GeomType represents the geometry type of a feature
+type GeomType int
+
+const (
+	Unknown GeomType = iota
+	Point
+	LineString
+	Polygon
+)
+
+var geomTypeStrings = [...]string{
+	"Unknown",
+	"Point",
+	"LineString",
+	"Polygon",
+}
+
+// String returns the GeoJSON name of the GeomType
+func (t GeomType) String() string {
+	if t < 0 || int(t) >= len(geomTypeStrings) {
+		return geomTypeStrings[Unknown]
+	}
+	return geomTypeStrings[t]
+}
+
+// Tile is a decoded vector tile
+type Tile struct {
+	Layers []*Layer
+}
+
+// Layer is a layer of a vector tile. The keys and values are shared by the
+// tags of all features.
+type Layer struct {
+	Version  uint32
+	Name     string
+	Extent   uint32
+	Keys     []string
+	Values   []Value
+	Features []*Feature
+}
+
+// Feature is a feature of a layer. The geometry is kept in its encoded form
+// of command and parameter integers.
+type Feature struct {
+	ID       uint64
+	HasID    bool
+	Tags     []uint32
+	Type     GeomType
+	Geometry []uint32
+}
+
+// Value is a typed attribute value
+type Value struct {
+	v interface{}
+}
+
+// NewValue creates a Value of a string, float32, float64, int64, uint64 or bool
+func NewValue(v interface{}) Value {
+	return Value{v}
+}
+
+// Interface returns the value as string, float32, float64, int64, uint64 or bool
+func (v Value) Interface() interface{} {
+	return v.v
+}
+
+// Layer returns the layer with the given name or nil
+func (t *Tile) Layer(name string) *Layer {
+	for _, l := range t.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Properties returns the tags of the feature as map
+func (l *Layer) Properties(f *Feature) map[string]interface{} {
+	props := make(map[string]interface{}, len(f.Tags)/2)
+	for i := 0; i+1 < len(f.Tags); i += 2 {
+		k, v := int(f.Tags[i]), int(f.Tags[i+1])
+		if k < len(l.Keys) && v < len(l.Values) {
+			props[l.Keys[k]] = l.Values[v].v
+		}
+	}
+	return props
+}
+
+// Decode decodes an uncompressed vector tile
+func Decode(data []byte) (*Tile, error) {
+	t := &Tile{}
+	r := &reader{buf: data}
+
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+
+		if field != 3 || wire != wireBytes {
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := decodeLayer(b)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode layer: %w", err)
+		}
+		t.Layers = append(t.Layers, l)
+	}
+
+	return t, nil
+}
+
+func decodeLayer(data []byte) (*Layer, error) {
+	l := &Layer{Version: 1, Extent: DefaultExtent}
+	r := &reader{buf: data}
+
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == 15 && wire == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			l.Version = uint32(v)
+		case field == 1 && wire == wireBytes:
+			var b []byte
+			b, err = r.bytes()
+			l.Name = string(b)
+		case field == 2 && wire == wireBytes:
+			var b []byte
+			if b, err = r.bytes(); err == nil {
+				var f *Feature
+				if f, err = decodeFeature(b); err == nil {
+					l.Features = append(l.Features, f)
+				}
+			}
+		case field == 3 && wire == wireBytes:
+			var b []byte
+			b, err = r.bytes()
+			l.Keys = append(l.Keys, string(b))
+		case field == 4 && wire == wireBytes:
+			var b []byte
+			if b, err = r.bytes(); err == nil {
+				var v Value
+				if v, err = decodeValue(b); err == nil {
+					l.Values = append(l.Values, v)
+				}
+			}
+		case field == 5 && wire == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			l.Extent = uint32(v)
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func decodeFeature(data []byte) (*Feature, error) {
+	f := &Feature{}
+	r := &reader{buf: data}
+
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == 1 && wire == wireVarint:
+			f.ID, err = r.varint()
+			f.HasID = true
+		case field == 2 && wire == wireBytes:
+			f.Tags, err = r.packed()
+		case field == 3 && wire == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			f.Type = GeomType(v)
+		case field == 4 && wire == wireBytes:
+			f.Geometry, err = r.packed()
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func decodeValue(data []byte) (Value, error) {
+	var v Value
+	r := &reader{buf: data}
+
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return v, err
+		}
+
+		switch {
+		case field == 1 && wire == wireBytes:
+			var b []byte
+			b, err = r.bytes()
+			v.v = string(b)
+		case field == 2 && wire == wireFixed32:
+			var u uint32
+			u, err = r.fixed32()
+			v.v = math.Float32frombits(u)
+		case field == 3 && wire == wireFixed64:
+			var u uint64
+			u, err = r.fixed64()
+			v.v = math.Float64frombits(u)
+		case field == 4 && wire == wireVarint:
+			var u uint64
+			u, err = r.varint()
+			v.v = int64(u)
+		case field == 5 && wire == wireVarint:
+			var u uint64
+			u, err = r.varint()
+			v.v = u
+		case field == 6 && wire == wireVarint:
+			var u uint64
+			u, err = r.varint()
+			v.v = unzigzag64(u)
+		case field == 7 && wire == wireVarint:
+			var u uint64
+			u, err = r.varint()
+			v.v = u != 0
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return v, err
+		}
+	}
+
+	return v, nil
+}
+
+// Encode encodes the tile without compression
+func Encode(t *Tile) ([]byte, error) {
+	var w writer
+
+	for _, l := range t.Layers {
+		b, err := encodeLayer(l)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode layer %q: %w", l.Name, err)
+		}
+		w.bytesField(3, b)
+	}
+
+	return w.buf, nil
+}
+
+func encodeLayer(l *Layer) ([]byte, error) {
+	var w writer
+
+	w.varintField(15, uint64(l.Version))
+	w.stringField(1, l.Name)
+
+	for _, f := range l.Features {
+		w.bytesField(2, encodeFeature(f))
+	}
+
+	for _, k := range l.Keys {
+		w.stringField(3, k)
+	}
+
+	for _, v := range l.Values {
+		b, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		w.bytesField(4, b)
+	}
+
+	w.varintField(5, uint64(l.Extent))
+
+	return w.buf, nil
+}
+
+func encodeFeature(f *Feature) []byte {
+	var w writer
+
+	if f.HasID {
+		w.varintField(1, f.ID)
+	}
+	w.packedField(2, f.Tags)
+	w.varintField(3, uint64(f.Type))
+	w.packedField(4, f.Geometry)
+
+	return w.buf
+}
+
+func encodeValue(v Value) ([]byte, error) {
+	var w writer
+
+	switch val := v.v.(type) {
+	case string:
+		w.stringField(1, val)
+	case float32:
+		w.floatField(2, val)
+	case float64:
+		w.doubleField(3, val)
+	case int64:
+		if val < 0 {
+			w.varintField(6, zigzag64(val))
+		} else {
+			w.varintField(4, uint64(val))
+		}
+	case uint64:
+		w.varintField(5, val)
+	case bool:
+		b := uint64(0)
+		if val {
+			b = 1
+		}
+		w.varintField(7, b)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v.v)
+	}
+
+	return w.buf, nil
+}
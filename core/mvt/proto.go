@@ -0,0 +1,177 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var (
+	ErrTruncated = errors.New("truncated protobuf message")
+	ErrWireType  = errors.New("unsupported protobuf wire type")
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// reader reads the fields of a protobuf message
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *reader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, ErrTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+// key returns the field number and wire type of the next field
+func (r *reader) key() (int, int, error) {
+	k, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(k >> 3), int(k & 7), nil
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(len(r.buf)-r.pos) {
+		return nil, ErrTruncated
+	}
+	b := r.buf[r.pos : r.pos+int(l)]
+	r.pos += int(l)
+	return b, nil
+}
+
+func (r *reader) fixed64() (uint64, error) {
+	if len(r.buf)-r.pos < 8 {
+		return 0, ErrTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf[r.pos:])
+	r.pos += 8
+	return v, nil
+}
+
+func (r *reader) fixed32() (uint32, error) {
+	if len(r.buf)-r.pos < 4 {
+		return 0, ErrTruncated
+	}
+	v := binary.LittleEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// skip skips the value of a field with the given wire type
+func (r *reader) skip(wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed32()
+	default:
+		err = ErrWireType
+	}
+	return err
+}
+
+// packed reads a packed repeated varint field
+func (r *reader) packed() ([]uint32, error) {
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &reader{buf: b}
+	values := make([]uint32, 0, len(b))
+	for !pr.done() {
+		v, err := pr.varint()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, uint32(v))
+	}
+
+	return values, nil
+}
+
+// writer writes the fields of a protobuf message
+type writer struct {
+	buf []byte
+}
+
+func (w *writer) varint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *writer) key(field, wire int) {
+	w.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (w *writer) varintField(field int, v uint64) {
+	w.key(field, wireVarint)
+	w.varint(v)
+}
+
+func (w *writer) bytesField(field int, b []byte) {
+	w.key(field, wireBytes)
+	w.varint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *writer) stringField(field int, s string) {
+	w.key(field, wireBytes)
+	w.varint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *writer) doubleField(field int, f float64) {
+	w.key(field, wireFixed64)
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(f))
+}
+
+func (w *writer) floatField(field int, f float32) {
+	w.key(field, wireFixed32)
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(f))
+}
+
+func (w *writer) packedField(field int, values []uint32) {
+	if len(values) == 0 {
+		return
+	}
+
+	var p writer
+	for _, v := range values {
+		p.varint(uint64(v))
+	}
+	w.bytesField(field, p.buf)
+}
+
+func zigzag64(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func unzigzag64(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
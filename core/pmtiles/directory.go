@@ -0,0 +1,133 @@
+package pmtiles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// entry represents a directory entry, which either points to a run of tiles
+// or, if RunLength is 0, to a leaf directory
+type entry struct {
+	TileID    uint64
+	Offset    uint64
+	Length    uint64
+	RunLength uint32
+}
+
+// decodeDirectory decodes an uncompressed directory. The entries are stored
+// column-wise as varints: delta-encoded tile IDs, run lengths, lengths and
+// offsets, where an offset of 0 means the data directly follows the
+// previous entry.
+func decodeDirectory(b []byte) ([]entry, error) {
+	var pos int
+
+	next := func() (uint64, error) {
+		v, n := binary.Uvarint(b[pos:])
+		if n <= 0 {
+			return 0, fmt.Errorf("%w: malformed varint at %v", ErrInvalidDirectory, pos)
+		}
+		pos += n
+		return v, nil
+	}
+
+	count, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	// every entry takes at least four bytes
+	if count > uint64(len(b))/4 {
+		return nil, fmt.Errorf("%w: entry count %v exceeds directory size", ErrInvalidDirectory, count)
+	}
+
+	entries := make([]entry, count)
+
+	var lastID uint64
+	for i := range entries {
+		v, err := next()
+		if err != nil {
+			return nil, err
+		}
+		lastID += v
+		entries[i].TileID = lastID
+	}
+
+	for i := range entries {
+		v, err := next()
+		if err != nil {
+			return nil, err
+		}
+		entries[i].RunLength = uint32(v)
+	}
+
+	for i := range entries {
+		v, err := next()
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Length = v
+	}
+
+	for i := range entries {
+		v, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if v == 0 && i > 0 {
+			entries[i].Offset = entries[i-1].Offset + entries[i-1].Length
+		} else {
+			entries[i].Offset = v - 1
+		}
+	}
+
+	return entries, nil
+}
+
+// findEntry returns the entry containing the tile ID or the leaf directory
+// entry covering it
+func findEntry(entries []entry, id uint64) (entry, bool) {
+	// index of the last entry with a tile ID less than or equal to id
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].TileID > id }) - 1
+	if i < 0 {
+		return entry{}, false
+	}
+
+	e := entries[i]
+	if e.RunLength == 0 || id-e.TileID < uint64(e.RunLength) {
+		return e, true
+	}
+
+	return entry{}, false
+}
+
+// zxyToID converts tile coordinates in XYZ scheme into a tile ID, which is
+// the position of the tile on a Hilbert curve plus the number of tiles of all
+// lower zoom levels
+func zxyToID(z uint8, x, y uint64) uint64 {
+	acc := ((uint64(1) << (2 * uint64(z))) - 1) / 3
+
+	n := uint64(1) << z
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint64
+		if x&s > 0 {
+			rx = 1
+		}
+		if y&s > 0 {
+			ry = 1
+		}
+
+		acc += s * s * ((3 * rx) ^ ry)
+
+		// rotate the quadrant
+		if ry == 0 {
+			if rx == 1 {
+				x = n - 1 - x
+				y = n - 1 - y
+			}
+			x, y = y, x
+		}
+	}
+
+	return acc
+}
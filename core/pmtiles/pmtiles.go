@@ -0,0 +1,514 @@
+// Package pmtiles implements a reader for PMTiles version 3 archives as described in
+// https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
+package pmtiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+)
+
+var (
+	ErrInvalidHeader          = errors.New("invalid archive header")
+	ErrUnsupportedVersion     = errors.New("unsupported archive version")
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+	ErrUnsupportedTileType    = errors.New("unsupported tile type")
+	ErrInvalidDirectory       = errors.New("invalid directory")
+	ErrOutOfBounds            = errors.New("section exceeds the archive")
+)
+
+// FileExtension is the file extension of PMTiles files
+const FileExtension = ".pmtiles"
+
+// Compression represents the compression type of the directories, metadata
+// and tiles of an archive
+type Compression uint8
+
+const (
+	UnknownCompression Compression = iota
+	NoCompression
+	Gzip
+	Brotli
+	Zstd
+)
+
+// Encoding returns the matching content encoding of the Compression,
+// unknown compression types are returned as identity
+func (c Compression) Encoding() compression.Encoding {
+	switch c {
+	case Gzip:
+		return compression.Gzip
+	case Brotli:
+		return compression.Brotli
+	case Zstd:
+		return compression.Zstd
+	default:
+		return compression.Identity
+	}
+}
+
+// TileType represents the type of the tiles of an archive
+type TileType uint8
+
+const (
+	UnknownTileType TileType = iota
+	MVT
+	PNG
+	JPEG
+	WEBP
+	AVIF
+)
+
+// TileFormat returns the matching TileFormat of the TileType
+func (t TileType) TileFormat() mbtiles.TileFormat {
+	switch t {
+	case MVT:
+		return mbtiles.PBF
+	case PNG:
+		return mbtiles.PNG
+	case JPEG:
+		return mbtiles.JPG
+	case WEBP:
+		return mbtiles.WEBP
+	default:
+		return mbtiles.UNKNOWN
+	}
+}
+
+const (
+	headerLength = 127
+	headerMagic  = "PMTiles"
+	version      = 3
+
+	// maxDirectoryDepth is the maximum number of directory levels
+	// (root and leaves) that have to be read to find a tile
+	maxDirectoryDepth = 4
+
+	// maxRootLength is the maximum length of the root directory, the header
+	// and the root directory must fit into the first 16 KiB of an archive
+	maxRootLength = 16384 - headerLength
+
+	// maxDirectoryLength limits the length of leaf directories, also after
+	// decompression
+	maxDirectoryLength = 32 << 20
+
+	// maxMetadataLength limits the length of the JSON metadata
+	maxMetadataLength = 16 << 20
+)
+
+// Header represents the fixed size header of an archive
+type Header struct {
+	RootOffset          uint64
+	RootLength          uint64
+	MetadataOffset      uint64
+	MetadataLength      uint64
+	LeafDirectoryOffset uint64
+	LeafDirectoryLength uint64
+	TileDataOffset      uint64
+	TileDataLength      uint64
+	AddressedTiles      uint64
+	TileEntries         uint64
+	TileContents        uint64
+	Clustered           bool
+	InternalCompression Compression
+	TileCompression     Compression
+	TileType            TileType
+	MinZoom             uint8
+	MaxZoom             uint8
+	MinLon, MinLat      float64
+	MaxLon, MaxLat      float64
+	CenterZoom          uint8
+	CenterLon           float64
+	CenterLat           float64
+}
+
+func parseHeader(b []byte) (*Header, error) {
+	if len(b) != headerLength || string(b[:7]) != headerMagic {
+		return nil, ErrInvalidHeader
+	}
+
+	if b[7] != version {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, b[7])
+	}
+
+	u64 := func(off int) uint64 { return binary.LittleEndian.Uint64(b[off : off+8]) }
+	e7 := func(off int) float64 { return float64(int32(binary.LittleEndian.Uint32(b[off:off+4]))) / 1e7 }
+
+	h := &Header{
+		RootOffset:          u64(8),
+		RootLength:          u64(16),
+		MetadataOffset:      u64(24),
+		MetadataLength:      u64(32),
+		LeafDirectoryOffset: u64(40),
+		LeafDirectoryLength: u64(48),
+		TileDataOffset:      u64(56),
+		TileDataLength:      u64(64),
+		AddressedTiles:      u64(72),
+		TileEntries:         u64(80),
+		TileContents:        u64(88),
+		Clustered:           b[96] == 1,
+		InternalCompression: Compression(b[97]),
+		TileCompression:     Compression(b[98]),
+		TileType:            TileType(b[99]),
+		MinZoom:             b[100],
+		MaxZoom:             b[101],
+		MinLon:              e7(102),
+		MinLat:              e7(106),
+		MaxLon:              e7(110),
+		MaxLat:              e7(114),
+		CenterZoom:          b[118],
+		CenterLon:           e7(119),
+		CenterLat:           e7(123),
+	}
+
+	return h, nil
+}
+
+// maxCachedLeaves is the number of leaf directories kept in memory
+const maxCachedLeaves = 64
+
+// Archive represents a PMTiles instance
+type Archive struct {
+	Filename    string
+	Format      mbtiles.TileFormat
+	Compression compression.Encoding
+	Timestamp   time.Time
+	Header      *Header
+
+	file *os.File
+	size uint64
+	root []entry
+
+	leavesMu sync.Mutex
+	leaves   map[uint64][]entry
+}
+
+// Open creates a new Archive by the given PMTiles file
+func Open(file string) (*Archive, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := newArchive(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func newArchive(f *os.File) (*Archive, error) {
+	fileStat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not read file stats for pmtiles file: %w", err)
+	}
+
+	b := make([]byte, headerLength)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	h, err := parseHeader(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSections(h, uint64(fileStat.Size())); err != nil {
+		return nil, err
+	}
+
+	switch h.InternalCompression {
+	case NoCompression, Gzip:
+	default:
+		return nil, fmt.Errorf("%w: internal compression %v", ErrUnsupportedCompression, h.InternalCompression)
+	}
+
+	format := h.TileType.TileFormat()
+	switch {
+	case format == mbtiles.UNKNOWN:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedTileType, h.TileType)
+	case format == mbtiles.PBF && h.TileCompression.Encoding() == compression.Identity && h.TileCompression != NoCompression:
+		return nil, fmt.Errorf("%w: tile compression %v", ErrUnsupportedCompression, h.TileCompression)
+	case format.IsRaster() && h.TileCompression != NoCompression && h.TileCompression != UnknownCompression:
+		return nil, fmt.Errorf("%w: raster tiles must not be compressed", ErrUnsupportedCompression)
+	}
+
+	a := &Archive{
+		Filename:    fileStat.Name(),
+		Format:      format,
+		Compression: h.TileCompression.Encoding(),
+		Timestamp:   fileStat.ModTime().Round(time.Second),
+		Header:      h,
+		file:        f,
+		size:        uint64(fileStat.Size()),
+		leaves:      make(map[uint64][]entry),
+	}
+
+	a.root, err = a.readDirectory(h.RootOffset, h.RootLength)
+	if err != nil {
+		return nil, fmt.Errorf("could not read root directory: %w", err)
+	}
+
+	return a, nil
+}
+
+// checkSections checks that the sections of the header are within the file
+// and that the directories and the metadata do not exceed their size limits,
+// so that a corrupt header does not lead to huge allocations
+func checkSections(h *Header, size uint64) error {
+	sections := []struct {
+		name           string
+		offset, length uint64
+		max            uint64
+	}{
+		{"root directory", h.RootOffset, h.RootLength, maxRootLength},
+		{"metadata", h.MetadataOffset, h.MetadataLength, maxMetadataLength},
+		{"leaf directories", h.LeafDirectoryOffset, h.LeafDirectoryLength, 0},
+		{"tile data", h.TileDataOffset, h.TileDataLength, 0},
+	}
+
+	for _, s := range sections {
+		if !inBounds(s.offset, s.length, size) {
+			return fmt.Errorf("%w: %s of %v bytes at offset %v, file size %v", ErrOutOfBounds, s.name, s.length, s.offset, size)
+		}
+		if s.max > 0 && s.length > s.max {
+			return fmt.Errorf("%w: %s of %v bytes exceeds the limit of %v bytes", ErrInvalidHeader, s.name, s.length, s.max)
+		}
+	}
+
+	return nil
+}
+
+// inBounds reports whether length bytes at offset are within size bytes
+func inBounds(offset, length, size uint64) bool {
+	return offset <= size && length <= size-offset
+}
+
+// GetTile reads a tile with tile identifiers z, x, y into []byte.
+func (a *Archive) GetTile(tc *mbtiles.TileCoord) ([]byte, error) {
+	if tc.Z < a.Header.MinZoom || tc.Z > a.Header.MaxZoom {
+		return nil, mbtiles.ErrTileNotFound
+	}
+
+	// TileCoord rows are in TMS scheme, PMTiles uses the XYZ scheme
+	y := (uint64(1) << tc.Z) - 1 - tc.Y
+
+	id := zxyToID(tc.Z, tc.X, y)
+
+	dir := a.root
+	for depth := 0; depth < maxDirectoryDepth; depth++ {
+		e, ok := findEntry(dir, id)
+		if !ok {
+			return nil, mbtiles.ErrTileNotFound
+		}
+
+		if e.RunLength > 0 {
+			return a.read(a.Header.TileDataOffset+e.Offset, e.Length)
+		}
+
+		var err error
+		if dir, err = a.getLeaf(e.Offset, e.Length); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, mbtiles.ErrTileNotFound
+}
+
+// GetGrid always returns ErrNoUTFGrid because archives do not contain UTF grids
+func (a *Archive) GetGrid(_ *mbtiles.TileCoord) ([]byte, error) {
+	return nil, mbtiles.ErrNoUTFGrid
+}
+
+type metadata struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	Attribution  string                 `json:"attribution"`
+	Version      string                 `json:"version"`
+	Type         string                 `json:"type"`
+	VectorLayers *[]mbtiles.VectorLayer `json:"vector_layers"`
+	TileStats    *mbtiles.TileStats     `json:"tilestats"`
+}
+
+// GetMetadata reads the JSON metadata and the header into Metadata
+func (a *Archive) GetMetadata() (*mbtiles.Metadata, error) {
+	h := a.Header
+
+	md := &mbtiles.Metadata{
+		Format:  a.Format,
+		MinZoom: int(h.MinZoom),
+		MaxZoom: int(h.MaxZoom),
+		Bounds:  [4]float64{h.MinLon, h.MinLat, h.MaxLon, h.MaxLat},
+		Center:  [3]float64{h.CenterLon, h.CenterLat, float64(h.CenterZoom)},
+	}
+
+	if h.MetadataLength == 0 {
+		return md, nil
+	}
+
+	data, err := a.read(h.MetadataOffset, h.MetadataLength)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := decompress(data, h.InternalCompression)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var m metadata
+	if err := json.NewDecoder(io.LimitReader(r, maxMetadataLength)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("could not decode metadata: %w", err)
+	}
+
+	md.Name = m.Name
+	md.Description = m.Description
+	md.Attribution = m.Attribution
+	md.Version = m.Version
+
+	if m.Type == mbtiles.Overlay.String() {
+		md.Type = mbtiles.Overlay
+	}
+
+	if m.VectorLayers != nil || m.TileStats != nil {
+		md.LayerData = &mbtiles.LayerData{
+			VectorLayers: m.VectorLayers,
+			TileStats:    m.TileStats,
+		}
+	}
+
+	return md, nil
+}
+
+// TileFormat returns the TileFormat of the Archive
+func (a *Archive) TileFormat() mbtiles.TileFormat {
+	return a.Format
+}
+
+// GridFormat always returns UNKNOWN because archives do not contain UTF grids
+func (a *Archive) GridFormat() mbtiles.TileFormat {
+	return mbtiles.UNKNOWN
+}
+
+// ModTime returns the modification time of the PMTiles file
+func (a *Archive) ModTime() time.Time {
+	return a.Timestamp
+}
+
+// TileCompression returns the compression of the tiles of the Archive
+func (a *Archive) TileCompression() compression.Encoding {
+	return a.Compression
+}
+
+// ContentType returns the content-type string of the TileFormat of the Archive.
+func (a *Archive) ContentType() string {
+	return a.Format.ContentType()
+}
+
+// Close closes the file of the Archive
+func (a *Archive) Close() error {
+	return a.file.Close()
+}
+
+// Ping checks that the header of the Archive can be read
+func (a *Archive) Ping(_ context.Context) error {
+	b, err := a.read(0, headerLength)
+	if err != nil {
+		return err
+	}
+
+	if _, err := parseHeader(b); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *Archive) read(offset, length uint64) ([]byte, error) {
+	if !inBounds(offset, length, a.size) {
+		return nil, fmt.Errorf("%w: %v bytes at offset %v", ErrOutOfBounds, length, offset)
+	}
+
+	b := make([]byte, length)
+	if _, err := a.file.ReadAt(b, int64(offset)); err != nil {
+		return nil, fmt.Errorf("could not read archive at offset %v: %w", offset, err)
+	}
+
+	return b, nil
+}
+
+func (a *Archive) readDirectory(offset, length uint64) ([]entry, error) {
+	if length > maxDirectoryLength {
+		return nil, fmt.Errorf("%w: length %v exceeds the limit", ErrInvalidDirectory, length)
+	}
+
+	data, err := a.read(offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := decompress(data, a.Header.InternalCompression)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(io.LimitReader(r, maxDirectoryLength+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress directory: %w", err)
+	}
+	if len(b) > maxDirectoryLength {
+		return nil, fmt.Errorf("%w: decompressed length exceeds the limit", ErrInvalidDirectory)
+	}
+
+	return decodeDirectory(b)
+}
+
+func (a *Archive) getLeaf(offset, length uint64) ([]entry, error) {
+	a.leavesMu.Lock()
+	dir, ok := a.leaves[offset]
+	a.leavesMu.Unlock()
+
+	if ok {
+		return dir, nil
+	}
+
+	dir, err := a.readDirectory(a.Header.LeafDirectoryOffset+offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("could not read leaf directory: %w", err)
+	}
+
+	a.leavesMu.Lock()
+	if len(a.leaves) >= maxCachedLeaves {
+		a.leaves = make(map[uint64][]entry)
+	}
+	a.leaves[offset] = dir
+	a.leavesMu.Unlock()
+
+	return dir, nil
+}
+
+func decompress(data []byte, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case NoCompression:
+		return io.NopCloser(bytes.NewReader(data)), nil
+	case Gzip:
+		return gzip.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedCompression, c)
+	}
+}
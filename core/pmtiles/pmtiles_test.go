@@ -0,0 +1,379 @@
+package pmtiles
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+)
+
+// encodeDirectory encodes the entries like decodeDirectory expects them,
+// offsets directly following the previous entry are stored as 0
+func encodeDirectory(entries []entry) []byte {
+	var b []byte
+	put := func(v uint64) { b = binary.AppendUvarint(b, v) }
+
+	put(uint64(len(entries)))
+
+	var lastID uint64
+	for _, e := range entries {
+		put(e.TileID - lastID)
+		lastID = e.TileID
+	}
+	for _, e := range entries {
+		put(uint64(e.RunLength))
+	}
+	for _, e := range entries {
+		put(e.Length)
+	}
+	for i, e := range entries {
+		if i > 0 && e.Offset == entries[i-1].Offset+entries[i-1].Length {
+			put(0)
+		} else {
+			put(e.Offset + 1)
+		}
+	}
+
+	return b
+}
+
+// encodeHeader encodes the header like parseHeader expects it
+func encodeHeader(h *Header) []byte {
+	b := make([]byte, headerLength)
+	copy(b, headerMagic)
+	b[7] = version
+
+	u64 := func(off int, v uint64) { binary.LittleEndian.PutUint64(b[off:], v) }
+	e7 := func(off int, v float64) { binary.LittleEndian.PutUint32(b[off:], uint32(int32(v*1e7))) }
+
+	u64(8, h.RootOffset)
+	u64(16, h.RootLength)
+	u64(24, h.MetadataOffset)
+	u64(32, h.MetadataLength)
+	u64(40, h.LeafDirectoryOffset)
+	u64(48, h.LeafDirectoryLength)
+	u64(56, h.TileDataOffset)
+	u64(64, h.TileDataLength)
+	if h.Clustered {
+		b[96] = 1
+	}
+	b[97] = byte(h.InternalCompression)
+	b[98] = byte(h.TileCompression)
+	b[99] = byte(h.TileType)
+	b[100] = h.MinZoom
+	b[101] = h.MaxZoom
+	e7(102, h.MinLon)
+	e7(106, h.MinLat)
+	e7(110, h.MaxLon)
+	e7(114, h.MaxLat)
+
+	return b
+}
+
+// archive is the content of a test archive with PNG tiles at z0 and z1, the
+// tiles of z1 are stored in a leaf directory
+type archive struct {
+	header   Header
+	root     []byte
+	metadata []byte
+	leaves   []byte
+	tiles    []byte
+}
+
+func newTestArchive() *archive {
+	tiles := []byte("\x89PNG z0\x89PNG z1")
+
+	// the tiles of z1 except 1/1/0 (ID 4) are a run of the same data
+	leaves := encodeDirectory([]entry{
+		{TileID: 1, Offset: 7, Length: 7, RunLength: 3},
+	})
+	root := encodeDirectory([]entry{
+		{TileID: 0, Offset: 0, Length: 7, RunLength: 1},
+		{TileID: 1, Offset: 0, Length: uint64(len(leaves))},
+	})
+	metadata := []byte(`{"name":"test","vector_layers":[{"id":"a","fields":{}}]}`)
+
+	a := &archive{root: root, metadata: metadata, leaves: leaves, tiles: tiles}
+	a.header = Header{
+		RootOffset:          headerLength,
+		RootLength:          uint64(len(root)),
+		MetadataOffset:      headerLength + uint64(len(root)),
+		MetadataLength:      uint64(len(metadata)),
+		LeafDirectoryOffset: headerLength + uint64(len(root)+len(metadata)),
+		LeafDirectoryLength: uint64(len(leaves)),
+		TileDataOffset:      headerLength + uint64(len(root)+len(metadata)+len(leaves)),
+		TileDataLength:      uint64(len(tiles)),
+		InternalCompression: NoCompression,
+		TileCompression:     NoCompression,
+		TileType:            PNG,
+		MinZoom:             0,
+		MaxZoom:             1,
+	}
+
+	return a
+}
+
+// write writes the archive into a temporary file
+func (a *archive) write(t *testing.T) string {
+	var b []byte
+	b = append(b, encodeHeader(&a.header)...)
+	b = append(b, a.root...)
+	b = append(b, a.metadata...)
+	b = append(b, a.leaves...)
+	b = append(b, a.tiles...)
+
+	file := filepath.Join(t.TempDir(), "test.pmtiles")
+	if err := os.WriteFile(file, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestZxyToID(t *testing.T) {
+	// values of the reference implementation of the specification
+	tests := []struct {
+		z    uint8
+		x, y uint64
+		want uint64
+	}{
+		{0, 0, 0, 0},
+		{1, 0, 0, 1},
+		{1, 0, 1, 2},
+		{1, 1, 1, 3},
+		{1, 1, 0, 4},
+		{2, 0, 0, 5},
+		{2, 0, 3, 10},
+		{2, 3, 3, 15},
+		{2, 3, 0, 20},
+		{12, 3423, 1763, 19078479},
+	}
+
+	for _, tt := range tests {
+		if got := zxyToID(tt.z, tt.x, tt.y); got != tt.want {
+			t.Errorf("zxyToID(%v, %v, %v) = %v, want %v", tt.z, tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// the IDs of a zoom level are consecutive and unique
+	seen := make(map[uint64]bool)
+	for x := uint64(0); x < 8; x++ {
+		for y := uint64(0); y < 8; y++ {
+			id := zxyToID(3, x, y)
+			if id < 21 || id >= 85 || seen[id] {
+				t.Fatalf("zxyToID(3, %v, %v) = %v, want a unique ID in [21, 85)", x, y, id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
+func TestDecodeDirectory(t *testing.T) {
+	entries := []entry{
+		{TileID: 0, Offset: 0, Length: 10, RunLength: 1},
+		{TileID: 1, Offset: 10, Length: 20, RunLength: 2},
+		{TileID: 5, Offset: 100, Length: 5, RunLength: 0},
+		{TileID: 1000, Offset: 105, Length: 1, RunLength: 1},
+	}
+
+	got, err := decodeDirectory(encodeDirectory(entries))
+	if err != nil {
+		t.Fatalf("decodeDirectory() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("decodeDirectory() = %v, want %v", got, entries)
+	}
+
+	invalid := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"count exceeds size", []byte{0xff, 0xff, 0x03, 0, 0, 0, 0}},
+		{"truncated", encodeDirectory(entries)[:8]},
+		{"malformed varint", []byte{1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeDirectory(tt.data); !errors.Is(err, ErrInvalidDirectory) {
+				t.Errorf("decodeDirectory() error = %v, want %v", err, ErrInvalidDirectory)
+			}
+		})
+	}
+}
+
+func TestFindEntry(t *testing.T) {
+	entries := []entry{
+		{TileID: 2, RunLength: 2},
+		{TileID: 10, RunLength: 0},
+		{TileID: 20, RunLength: 1},
+	}
+
+	tests := []struct {
+		id   uint64
+		want uint64
+		ok   bool
+	}{
+		{1, 0, false},
+		{2, 2, true},
+		{3, 2, true},
+		{4, 0, false},
+		{15, 10, true},
+		{20, 20, true},
+		{21, 0, false},
+	}
+
+	for _, tt := range tests {
+		e, ok := findEntry(entries, tt.id)
+		if ok != tt.ok || (ok && e.TileID != tt.want) {
+			t.Errorf("findEntry(%v) = %v %v, want %v %v", tt.id, e.TileID, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	a, err := Open(newTestArchive().write(t))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer a.Close()
+
+	if a.Format != mbtiles.PNG {
+		t.Errorf("Format = %v, want %v", a.Format, mbtiles.PNG)
+	}
+
+	// TileCoord rows are in TMS scheme
+	tests := []struct {
+		tc   mbtiles.TileCoord
+		want string
+		err  error
+	}{
+		{mbtiles.TileCoord{Z: 0, X: 0, Y: 0}, "\x89PNG z0", nil},
+		{mbtiles.TileCoord{Z: 1, X: 0, Y: 1}, "\x89PNG z1", nil},
+		{mbtiles.TileCoord{Z: 1, X: 0, Y: 0}, "\x89PNG z1", nil},
+		{mbtiles.TileCoord{Z: 1, X: 1, Y: 0}, "\x89PNG z1", nil},
+		{mbtiles.TileCoord{Z: 1, X: 1, Y: 1}, "", mbtiles.ErrTileNotFound},
+		{mbtiles.TileCoord{Z: 2, X: 0, Y: 0}, "", mbtiles.ErrTileNotFound},
+	}
+
+	for _, tt := range tests {
+		data, err := a.GetTile(&tt.tc)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("GetTile(%v) error = %v, want %v", tt.tc, err, tt.err)
+			continue
+		}
+		if string(data) != tt.want {
+			t.Errorf("GetTile(%v) = %q, want %q", tt.tc, data, tt.want)
+		}
+	}
+
+	md, err := a.GetMetadata()
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if md.Name != "test" || md.LayerData == nil || len(*md.LayerData.VectorLayers) != 1 {
+		t.Errorf("GetMetadata() = %+v, want the name and vector layers of the metadata", md)
+	}
+}
+
+func TestOpenCorrupt(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *archive)
+		err    error
+	}{
+		{
+			name:   "huge root length",
+			modify: func(a *archive) { a.header.RootLength = 1 << 62 },
+			err:    ErrOutOfBounds,
+		},
+		{
+			name:   "root length exceeds the first 16 KiB",
+			modify: func(a *archive) { a.header.RootLength = maxRootLength + 1; a.tiles = make([]byte, 1<<15) },
+			err:    ErrInvalidHeader,
+		},
+		{
+			name:   "huge metadata length",
+			modify: func(a *archive) { a.header.MetadataLength = 1<<64 - 1 },
+			err:    ErrOutOfBounds,
+		},
+		{
+			name:   "metadata beyond the file",
+			modify: func(a *archive) { a.header.MetadataOffset = 1 << 40 },
+			err:    ErrOutOfBounds,
+		},
+		{
+			name:   "offset overflows with the length",
+			modify: func(a *archive) { a.header.TileDataOffset, a.header.TileDataLength = 1<<64-1, 2 },
+			err:    ErrOutOfBounds,
+		},
+		{
+			name:   "truncated tile data",
+			modify: func(a *archive) { a.tiles = a.tiles[:3] },
+			err:    ErrOutOfBounds,
+		},
+		{
+			name:   "corrupt root directory",
+			modify: func(a *archive) { a.root[0] = 0x7f },
+			err:    ErrInvalidDirectory,
+		},
+		{
+			name:   "unsupported internal compression",
+			modify: func(a *archive) { a.header.InternalCompression = Brotli },
+			err:    ErrUnsupportedCompression,
+		},
+		{
+			name:   "unsupported tile type",
+			modify: func(a *archive) { a.header.TileType = AVIF },
+			err:    ErrUnsupportedTileType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestArchive()
+			tt.modify(a)
+
+			if _, err := Open(a.write(t)); !errors.Is(err, tt.err) {
+				t.Errorf("Open() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	file := filepath.Join(t.TempDir(), "short.pmtiles")
+	if err := os.WriteFile(file, []byte(headerMagic), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(file); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("Open() of a truncated header error = %v, want %v", err, ErrInvalidHeader)
+	}
+}
+
+func TestCorruptLeaf(t *testing.T) {
+	a := newTestArchive()
+
+	// the leaf entry points beyond the leaf directories and the file
+	a.root = encodeDirectory([]entry{
+		{TileID: 0, Offset: 0, Length: 7, RunLength: 1},
+		{TileID: 1, Offset: 1 << 40, Length: 1 << 20},
+	})
+	a.header.RootLength = uint64(len(a.root))
+	a.header.MetadataOffset = headerLength + a.header.RootLength
+	a.header.LeafDirectoryOffset = a.header.MetadataOffset + a.header.MetadataLength
+	a.header.TileDataOffset = a.header.LeafDirectoryOffset + a.header.LeafDirectoryLength
+
+	ar, err := Open(a.write(t))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer ar.Close()
+
+	if _, err := ar.GetTile(&mbtiles.TileCoord{Z: 1}); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("GetTile() error = %v, want %v", err, ErrOutOfBounds)
+	}
+}
@@ -8,18 +8,16 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/tarkov-database/tileserver/route"
+	"github.com/tarkov-database/tileserver/core/config"
 
 	"github.com/google/logger"
 )
 
-// ListenAndServe starts the HTTP server
-func ListenAndServe() error {
-	mux := route.Load()
-
+// ListenAndServe starts the HTTP server with the given handler
+func ListenAndServe(cfg config.Server, h http.Handler) error {
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%v", cfg.Port),
-		Handler: mux,
+		Handler: h,
 	}
 
 	idleConnsClosed := make(chan struct{})
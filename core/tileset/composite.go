@@ -0,0 +1,334 @@
+package tileset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+
+	"github.com/google/logger"
+)
+
+// Composite is a Provider that merges the vector tiles of other providers of
+// the registry. The sources are looked up on every call, so that reloaded
+// sources are used immediately and missing ones are skipped.
+type Composite struct {
+	id      string
+	sources []string
+}
+
+// dependents maps the IDs of providers to the composites using them
+var dependents = map[string][]string{}
+
+// RegisterComposite adds a Composite of the given sources to the registry.
+// Files with the same ID are not loaded. It must not be called concurrently
+// with loading.
+func RegisterComposite(id string, sources []string) {
+	if IsReserved(id) {
+		logger.Errorf("Composite tileset ID \"%s\" is reserved, skipping it", id)
+		return
+	}
+
+	for _, src := range sources {
+		dependents[src] = append(dependents[src], id)
+	}
+
+	Register(id, &Composite{id: id, sources: sources})
+}
+
+// validateComposites logs the problems of the composites using one of the
+// changed providers, like layers hidden by a layer of another source
+func validateComposites(changed map[string]struct{}) {
+	checked := make(map[string]struct{})
+	for src := range changed {
+		for _, id := range dependents[src] {
+			if _, ok := checked[id]; ok {
+				continue
+			}
+			checked[id] = struct{}{}
+
+			h, err := Get(id)
+			if err != nil {
+				continue
+			}
+			if v, ok := h.Provider.(Validator); ok {
+				for _, p := range v.Validate() {
+					logger.Warningf("Composite tileset \"%s\": %s", id, p)
+				}
+			}
+			h.Release()
+		}
+	}
+}
+
+// Sources returns the IDs of the source providers
+func (c *Composite) Sources() []string {
+	return c.sources
+}
+
+// each calls f with every registered source of the composite that has vector
+// tiles, in the order of the sources
+func (c *Composite) each(f func(id string, h *Handle) error) error {
+	for _, id := range c.sources {
+		h, err := Get(id)
+		if err != nil {
+			continue
+		}
+
+		if h.TileFormat() != mbtiles.PBF {
+			h.Release()
+			continue
+		}
+
+		err = f(id, h)
+		h.Release()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTile returns the layers of the tiles of all sources in one vector tile.
+// Layers with the same name as a layer of a previous source are skipped,
+// Validate reports them.
+func (c *Composite) GetTile(tc *mbtiles.TileCoord) ([]byte, error) {
+	merged := &mvt.Tile{}
+	names := make(map[string]struct{})
+
+	var found bool
+	err := c.each(func(id string, h *Handle) error {
+		data, err := h.GetTile(tc)
+		if errors.Is(err, mbtiles.ErrTileNotFound) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("source %q: %w", id, err)
+		}
+		found = true
+
+		if data, err = compression.Decode(data, h.TileCompression()); err != nil {
+			return fmt.Errorf("source %q: %w", id, err)
+		}
+
+		t, err := mvt.Decode(data)
+		if err != nil {
+			return fmt.Errorf("source %q: could not decode vector tile: %w", id, err)
+		}
+
+		for _, l := range t.Layers {
+			if _, ok := names[l.Name]; ok {
+				continue
+			}
+			names[l.Name] = struct{}{}
+			merged.Layers = append(merged.Layers, l)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, mbtiles.ErrTileNotFound
+	}
+
+	data, err := mvt.Encode(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	return compression.Encode(data, compression.Gzip)
+}
+
+// Validate reports the vector layers of the sources that are hidden by a
+// layer with the same name of a previous source
+func (c *Composite) Validate() []error {
+	var problems []error
+	owners := make(map[string]string)
+
+	err := c.each(func(id string, h *Handle) error {
+		md, err := h.GetMetadata()
+		if err != nil {
+			return fmt.Errorf("source %q: %w", id, err)
+		}
+
+		if md.LayerData == nil || md.LayerData.VectorLayers == nil {
+			return nil
+		}
+
+		for _, l := range *md.LayerData.VectorLayers {
+			if owner, ok := owners[l.ID]; ok {
+				problems = append(problems, fmt.Errorf("layer %q of source %q is hidden by source %q", l.ID, id, owner))
+				continue
+			}
+			owners[l.ID] = id
+		}
+
+		return nil
+	})
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// GetGrid returns mbtiles.ErrNoUTFGrid, composites have no grids
+func (c *Composite) GetGrid(_ *mbtiles.TileCoord) ([]byte, error) {
+	return nil, mbtiles.ErrNoUTFGrid
+}
+
+// GetMetadata merges the metadata of the sources. The zoom range and bounds
+// cover all sources and the vector layers are concatenated.
+func (c *Composite) GetMetadata() (*mbtiles.Metadata, error) {
+	md := &mbtiles.Metadata{
+		Name:        c.id,
+		Format:      mbtiles.PBF,
+		Description: "Composite of " + strings.Join(c.sources, ", "),
+		MinZoom:     -1,
+	}
+
+	var layers []mbtiles.VectorLayer
+	var attributions []string
+	seen := make(map[string]struct{})
+
+	err := c.each(func(id string, h *Handle) error {
+		smd, err := h.GetMetadata()
+		if err != nil {
+			return fmt.Errorf("source %q: %w", id, err)
+		}
+
+		if md.MinZoom < 0 || smd.MinZoom < md.MinZoom {
+			md.MinZoom = smd.MinZoom
+		}
+		if smd.MaxZoom > md.MaxZoom {
+			md.MaxZoom = smd.MaxZoom
+		}
+		if smd.FillZoom != nil && (md.FillZoom == nil || *smd.FillZoom > *md.FillZoom) {
+			md.FillZoom = smd.FillZoom
+		}
+
+		md.Bounds = unionBounds(md.Bounds, smd.Bounds)
+		if md.Center == [3]float64{} {
+			md.Center = smd.Center
+		}
+
+		if a := smd.Attribution; a != "" {
+			if _, ok := seen["attribution:"+a]; !ok {
+				seen["attribution:"+a] = struct{}{}
+				attributions = append(attributions, a)
+			}
+		}
+
+		if smd.LayerData != nil && smd.LayerData.VectorLayers != nil {
+			for _, l := range *smd.LayerData.VectorLayers {
+				if _, ok := seen["layer:"+l.ID]; !ok {
+					seen["layer:"+l.ID] = struct{}{}
+					layers = append(layers, l)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if md.MinZoom < 0 {
+		return nil, fmt.Errorf("%w: no source of composite %q is loaded", mbtiles.ErrTilesetNotFound, c.id)
+	}
+
+	md.Attribution = strings.Join(attributions, ", ")
+	if layers != nil {
+		md.LayerData = &mbtiles.LayerData{VectorLayers: &layers}
+	}
+
+	return md, nil
+}
+
+// unionBounds returns the bounds covering a and b, zero bounds are unset
+func unionBounds(a, b [4]float64) [4]float64 {
+	switch {
+	case b == [4]float64{}:
+		return a
+	case a == [4]float64{}:
+		return b
+	}
+
+	for i := 0; i < 2; i++ {
+		if b[i] < a[i] {
+			a[i] = b[i]
+		}
+		if b[i+2] > a[i+2] {
+			a[i+2] = b[i+2]
+		}
+	}
+
+	return a
+}
+
+// TileFormat returns mbtiles.PBF
+func (c *Composite) TileFormat() mbtiles.TileFormat {
+	return mbtiles.PBF
+}
+
+// TileCompression returns compression.Gzip, the merged tiles are compressed
+// like the vector tiles of MBTiles
+func (c *Composite) TileCompression() compression.Encoding {
+	return compression.Gzip
+}
+
+// GridFormat returns mbtiles.UNKNOWN
+func (c *Composite) GridFormat() mbtiles.TileFormat {
+	return mbtiles.UNKNOWN
+}
+
+// ModTime returns the latest modification time of the sources
+func (c *Composite) ModTime() time.Time {
+	var t time.Time
+	c.each(func(_ string, h *Handle) error {
+		if m := h.ModTime(); m.After(t) {
+			t = m
+		}
+		return nil
+	})
+
+	return t
+}
+
+// Ping checks that all sources are loaded, healthy and have vector tiles
+func (c *Composite) Ping(ctx context.Context) error {
+	for _, id := range c.sources {
+		h, err := Get(id)
+		if err != nil {
+			return fmt.Errorf("source %q is not loaded", id)
+		}
+
+		if h.TileFormat() != mbtiles.PBF {
+			err = errors.New("no vector tiles")
+		} else if p, ok := h.Provider.(Pinger); ok {
+			err = p.Ping(ctx)
+		}
+		h.Release()
+
+		if err != nil {
+			return fmt.Errorf("source %q: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// Close does nothing, the sources are owned by the registry
+func (c *Composite) Close() error {
+	return nil
+}
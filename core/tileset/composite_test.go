@@ -0,0 +1,216 @@
+package tileset
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+)
+
+// vectorProvider returns a provider of a single vector tile at 0/0/0. Every
+// layer has one feature with the name of the provider as source property.
+func vectorProvider(t *testing.T, name string, layers []string, md mbtiles.Metadata) *tilesettest.Provider {
+	vt := &mvt.Tile{}
+	vectorLayers := make([]mbtiles.VectorLayer, len(layers))
+	for i, l := range layers {
+		f := &mvt.Feature{Type: mvt.Point, Tags: []uint32{0, 0}}
+		f.EncodeGeometry([][]mvt.Coord{{{X: 1, Y: 1}}})
+
+		vt.Layers = append(vt.Layers, &mvt.Layer{
+			Version:  2,
+			Name:     l,
+			Extent:   mvt.DefaultExtent,
+			Keys:     []string{"source"},
+			Values:   []mvt.Value{mvt.NewValue(name)},
+			Features: []*mvt.Feature{f},
+		})
+		vectorLayers[i] = mbtiles.VectorLayer{ID: l}
+	}
+	md.LayerData = &mbtiles.LayerData{VectorLayers: &vectorLayers}
+
+	return &tilesettest.Provider{
+		Metadata:    md,
+		Format:      mbtiles.PBF,
+		Compression: compression.Gzip,
+		Tiles:       map[mbtiles.TileCoord][]byte{{}: tilesettest.VectorTile(t, vt, compression.Gzip)},
+	}
+}
+
+// mergedLayers returns the names of the layers of the merged tile at 0/0/0
+// and their source property
+func mergedLayers(t *testing.T, h *Handle) ([]string, map[string]interface{}) {
+	data, err := h.GetTile(&mbtiles.TileCoord{})
+	if err != nil {
+		t.Fatalf("GetTile() error = %v", err)
+	}
+	if data, err = compression.Decode(data, h.TileCompression()); err != nil {
+		t.Fatal(err)
+	}
+	vt, err := mvt.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	sources := make(map[string]interface{})
+	for _, l := range vt.Layers {
+		names = append(names, l.Name)
+		sources[l.Name] = l.Properties(l.Features[0])["source"]
+	}
+
+	return names, sources
+}
+
+func TestComposite(t *testing.T) {
+	unregister(t, "composite", "composite-a", "composite-b", "composite-raster")
+
+	Register("composite-a", vectorProvider(t, "a", []string{"roads", "labels"}, mbtiles.Metadata{
+		MinZoom: 2, MaxZoom: 4, Attribution: "Tarkov Database",
+		Bounds: [4]float64{-10, -10, 10, 10}, Center: [3]float64{1, 1, 2},
+	}))
+	Register("composite-b", vectorProvider(t, "b", []string{"labels", "loot"}, mbtiles.Metadata{
+		MinZoom: 0, MaxZoom: 6, Attribution: "Tarkov Database",
+		Bounds: [4]float64{-20, 0, 5, 20},
+	}))
+	Register("composite-raster", &tilesettest.Provider{Format: mbtiles.PNG})
+	RegisterComposite("composite", []string{"composite-a", "composite-missing", "composite-raster", "composite-b"})
+
+	h, err := Get("composite")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer h.Release()
+
+	t.Run("GetTile", func(t *testing.T) {
+		// a layer of a later source with the name of a previous one is skipped
+		names, got := mergedLayers(t, h)
+		if want := []string{"roads", "labels", "loot"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("layers = %v, want %v", names, want)
+		}
+		if want := map[string]interface{}{"roads": "a", "labels": "a", "loot": "b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("layer sources = %v, want %v", got, want)
+		}
+
+		if _, err := h.GetTile(&mbtiles.TileCoord{Z: 1}); !errors.Is(err, mbtiles.ErrTileNotFound) {
+			t.Errorf("GetTile() of a missing tile error = %v, want %v", err, mbtiles.ErrTileNotFound)
+		}
+	})
+
+	t.Run("GetMetadata", func(t *testing.T) {
+		md, err := h.GetMetadata()
+		if err != nil {
+			t.Fatalf("GetMetadata() error = %v", err)
+		}
+
+		if md.MinZoom != 0 || md.MaxZoom != 6 {
+			t.Errorf("zoom range = %v-%v, want 0-6", md.MinZoom, md.MaxZoom)
+		}
+		if want := [4]float64{-20, -10, 10, 20}; md.Bounds != want {
+			t.Errorf("Bounds = %v, want %v", md.Bounds, want)
+		}
+		if want := [3]float64{1, 1, 2}; md.Center != want {
+			t.Errorf("Center = %v, want %v", md.Center, want)
+		}
+		if md.Attribution != "Tarkov Database" {
+			t.Errorf("Attribution = %q, want %q", md.Attribution, "Tarkov Database")
+		}
+
+		var ids []string
+		for _, l := range *md.LayerData.VectorLayers {
+			ids = append(ids, l.ID)
+		}
+		if want := []string{"roads", "labels", "loot"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("vector layers = %v, want %v", ids, want)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		problems := h.Provider.(Validator).Validate()
+		if len(problems) != 1 {
+			t.Fatalf("Validate() = %v, want one problem", problems)
+		}
+
+		want := `layer "labels" of source "composite-b" is hidden by source "composite-a"`
+		if !strings.Contains(problems[0].Error(), want) {
+			t.Errorf("Validate() = %q, want %q", problems[0], want)
+		}
+	})
+}
+
+func TestCompositeWithoutSources(t *testing.T) {
+	unregister(t, "composite-empty")
+	RegisterComposite("composite-empty", []string{"composite-none"})
+
+	h, err := Get("composite-empty")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer h.Release()
+
+	if _, err := h.GetMetadata(); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+		t.Errorf("GetMetadata() error = %v, want %v", err, mbtiles.ErrTilesetNotFound)
+	}
+	if _, err := h.GetTile(&mbtiles.TileCoord{}); !errors.Is(err, mbtiles.ErrTileNotFound) {
+		t.Errorf("GetTile() error = %v, want %v", err, mbtiles.ErrTileNotFound)
+	}
+	if problems := h.Provider.(Validator).Validate(); len(problems) != 0 {
+		t.Errorf("Validate() = %v, want no problems", problems)
+	}
+}
+
+func TestCompositeSourceReload(t *testing.T) {
+	unregister(t, "reload", "reload-a", "reload-b")
+
+	Register("reload-a", vectorProvider(t, "a", []string{"roads"}, mbtiles.Metadata{}))
+	Register("reload-b", vectorProvider(t, "b", []string{"loot"}, mbtiles.Metadata{}))
+	RegisterComposite("reload", []string{"reload-a", "reload-b"})
+
+	h, err := Get("reload")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer h.Release()
+
+	if _, got := mergedLayers(t, h); !reflect.DeepEqual(got, map[string]interface{}{"roads": "a", "loot": "b"}) {
+		t.Fatalf("merged layers = %v", got)
+	}
+
+	// a reloaded source changes the generation and the merged tile
+	Register("reload-b", vectorProvider(t, "b2", []string{"loot", "labels"}, mbtiles.Metadata{}))
+
+	h2, err := Get("reload")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer h2.Release()
+
+	if h2.Generation() == h.Generation() {
+		t.Error("generation of the composite unchanged after a source was reloaded")
+	}
+
+	want := map[string]interface{}{"roads": "a", "loot": "b2", "labels": "b2"}
+	if _, got := mergedLayers(t, h2); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged layers = %v, want %v", got, want)
+	}
+
+	// a removed source changes the generation as well
+	Unregister("reload-a")
+
+	h3, err := Get("reload")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer h3.Release()
+
+	if h3.Generation() == h2.Generation() {
+		t.Error("generation of the composite unchanged after a source was removed")
+	}
+	if names, _ := mergedLayers(t, h3); !reflect.DeepEqual(names, []string{"loot", "labels"}) {
+		t.Errorf("merged layers = %v, want the layers of the remaining source", names)
+	}
+}
@@ -0,0 +1,70 @@
+package tileset
+
+import (
+	"sync/atomic"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+
+	"github.com/google/logger"
+)
+
+// Indexer is implemented by providers that can persist the hashes of their
+// tiles in an ETag index
+type Indexer interface {
+	// TileHash returns the indexed hash of a tile or false if it is not indexed
+	TileHash(tc *mbtiles.TileCoord) ([32]byte, bool)
+
+	// HasETagIndex reports whether an up to date index exists
+	HasETagIndex() bool
+
+	// BuildETagIndex builds the index of all tiles
+	BuildETagIndex() error
+}
+
+var (
+	etagIndexing atomic.Bool
+
+	// indexQueue limits the index jobs to one at a time
+	indexQueue = make(chan struct{}, 1)
+)
+
+// SetETagIndexing enables or disables building missing ETag indexes in the
+// background when tilesets are loaded
+func SetETagIndexing(enabled bool) {
+	etagIndexing.Store(enabled)
+}
+
+// buildIndex builds the ETag index of the Provider in the background if it
+// is missing
+func buildIndex(id string, h *Handle) {
+	if !etagIndexing.Load() {
+		return
+	}
+
+	idx, ok := h.Provider.(Indexer)
+	if !ok || idx.HasETagIndex() {
+		return
+	}
+
+	h.acquire()
+
+	go func() {
+		defer h.Release()
+
+		indexQueue <- struct{}{}
+		defer func() { <-indexQueue }()
+
+		if h.isRetired() {
+			return
+		}
+
+		logger.Infof("Building ETag index of tileset \"%s\"", id)
+
+		if err := idx.BuildETagIndex(); err != nil {
+			logger.Errorf("Building ETag index of tileset \"%s\" failed: %s", id, err)
+			return
+		}
+
+		logger.Infof("ETag index of tileset \"%s\" built", id)
+	}()
+}
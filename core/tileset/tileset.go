@@ -0,0 +1,515 @@
+// Package tileset provides a registry of tile providers of different archive types
+package tileset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/pmtiles"
+
+	"github.com/google/logger"
+)
+
+// Provider represents a source of tiles and their metadata
+type Provider interface {
+	// GetTile returns the tile data of the given coordinates or
+	// mbtiles.ErrTileNotFound if the tile does not exist
+	GetTile(tc *mbtiles.TileCoord) ([]byte, error)
+
+	// GetGrid returns the UTF grid of the given coordinates or
+	// mbtiles.ErrNoUTFGrid if the provider has no grids
+	GetGrid(tc *mbtiles.TileCoord) ([]byte, error)
+
+	// GetMetadata returns the metadata of the tileset
+	GetMetadata() (*mbtiles.Metadata, error)
+
+	// TileFormat returns the format of the tiles
+	TileFormat() mbtiles.TileFormat
+
+	// TileCompression returns the content encoding the tiles are stored in
+	TileCompression() compression.Encoding
+
+	// GridFormat returns the compression of the UTF grids, mbtiles.JSON for
+	// uncompressed grids or mbtiles.UNKNOWN if the provider has no grids
+	GridFormat() mbtiles.TileFormat
+
+	// ModTime returns the time of the last modification of the tileset
+	ModTime() time.Time
+
+	// Close releases the resources of the provider
+	Close() error
+}
+
+// ErrUnsupportedFile is returned for files without an Opener
+var ErrUnsupportedFile = errors.New("unsupported file type")
+
+// Validator is implemented by providers that can check the integrity of
+// their data
+type Validator interface {
+	Validate() []error
+}
+
+// Extractor is implemented by providers that can write the tiles of an area
+// into a new MBTiles file
+type Extractor interface {
+	Extract(file string, opts mbtiles.ExtractOptions) error
+}
+
+// Analyzer is implemented by providers that can compute statistics of their
+// tiles
+type Analyzer interface {
+	Stats() (*mbtiles.Stats, error)
+}
+
+// Pinger is implemented by providers that can check the health of their
+// underlying storage
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Opener creates a Provider by the given file
+type Opener func(file string) (Provider, error)
+
+var openers = map[string]Opener{
+	mbtiles.FileExtension: openMBTiles,
+	pmtiles.FileExtension: openPMTiles,
+}
+
+func openMBTiles(file string) (Provider, error) {
+	ts, err := mbtiles.NewTileset(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+func openPMTiles(file string) (Provider, error) {
+	a, err := pmtiles.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Open creates a Provider by the given file with the Opener of its extension
+func Open(file string) (Provider, error) {
+	open, ok := openers[filepath.Ext(file)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFile, filepath.Base(file))
+	}
+
+	return open(file)
+}
+
+// IsSupported reports whether there is an Opener for the extension of the file
+func IsSupported(file string) bool {
+	_, ok := openers[filepath.Ext(file)]
+	return ok
+}
+
+// RegisterOpener adds an Opener for files with the given extension
+func RegisterOpener(ext string, o Opener) {
+	openers[ext] = o
+}
+
+// reserved holds the IDs that collide with other resources of the API
+var reserved = map[string]struct{}{}
+
+// Reserve marks the IDs as not usable by tilesets, files and composites with
+// such an ID are not loaded. It must be called before loading.
+func Reserve(ids ...string) {
+	for _, id := range ids {
+		reserved[id] = struct{}{}
+	}
+}
+
+// IsReserved reports whether the ID is not usable by tilesets
+func IsReserved(id string) bool {
+	_, ok := reserved[id]
+	return ok
+}
+
+// Handle is a reference counted Provider of the registry. It must be released
+// after use so that a replaced or removed Provider can be closed once all
+// in-flight requests are finished.
+type Handle struct {
+	Provider
+
+	id string
+
+	// generation is unique for every Handle
+	generation uint64
+
+	// file information of file based providers
+	file    string
+	modTime time.Time
+	size    int64
+
+	mu      sync.Mutex
+	refs    int
+	retired bool
+}
+
+var generations atomic.Uint64
+
+func newHandle(id string, p Provider) *Handle {
+	return &Handle{Provider: p, id: id, generation: generations.Add(1)}
+}
+
+// Generation returns a number that identifies the Handle, a replaced
+// Provider of the same ID and a Composite with a changed source get a new one
+func (h *Handle) Generation() uint64 {
+	return h.generation
+}
+
+// FileSize returns the size of the file of a file based Provider or 0
+func (h *Handle) FileSize() int64 {
+	return h.size
+}
+
+func (h *Handle) acquire() {
+	h.mu.Lock()
+	h.refs++
+	h.mu.Unlock()
+}
+
+// Release releases the reference to the Provider
+func (h *Handle) Release() {
+	h.mu.Lock()
+	h.refs--
+	closable := h.retired && h.refs == 0
+	h.mu.Unlock()
+
+	if closable {
+		h.close()
+	}
+}
+
+// retire marks the Provider as removed from the registry and closes it if
+// there are no references left
+func (h *Handle) retire() {
+	h.mu.Lock()
+	h.retired = true
+	closable := h.refs == 0
+	h.mu.Unlock()
+
+	if closable {
+		h.close()
+	}
+}
+
+func (h *Handle) isRetired() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.retired
+}
+
+func (h *Handle) close() {
+	if err := h.Provider.Close(); err != nil {
+		logger.Errorf("Closing tileset \"%s\" failed: %s", h.id, err)
+	}
+}
+
+var (
+	providers   = map[string]*Handle{}
+	providersMu sync.RWMutex
+)
+
+// listeners are called after a Provider was replaced or removed
+var listeners []func(id string)
+
+// OnChange adds a function that is called with the ID of a Provider after it
+// was replaced or removed, or one of the sources of a Composite changed. It
+// must not be called concurrently with loading.
+func OnChange(f func(id string)) {
+	listeners = append(listeners, f)
+}
+
+// Register adds a Provider with the given ID to the registry and replaces
+// an existing one
+func Register(id string, p Provider) {
+	swap(id, newHandle(id, p))
+}
+
+// Unregister removes the Provider with the given ID from the registry, it is
+// closed once all references are released
+func Unregister(id string) {
+	swap(id, nil)
+}
+
+// swap replaces the Provider of the given ID, nil removes it. The composites
+// using the Provider get a new Handle, so that their generation changes
+// whenever one of their sources is added, replaced or removed.
+func swap(id string, h *Handle) {
+	providersMu.Lock()
+	old, ok := providers[id]
+	if h != nil {
+		providers[id] = h
+	} else {
+		delete(providers, id)
+	}
+
+	var renewed []*Handle
+	for _, dep := range dependents[id] {
+		if c, ok := providers[dep]; ok {
+			providers[dep] = newHandle(dep, c.Provider)
+			renewed = append(renewed, c)
+		}
+	}
+	providersMu.Unlock()
+
+	ids := dependents[id]
+	if ok {
+		old.retire()
+		ids = append([]string{id}, ids...)
+	}
+	for _, c := range renewed {
+		c.retire()
+	}
+
+	for _, id := range ids {
+		for _, f := range listeners {
+			f(id)
+		}
+	}
+}
+
+// Get returns a Provider by the given ID. The returned Handle must be
+// released after use.
+func Get(id string) (*Handle, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	if h, ok := providers[id]; ok {
+		h.acquire()
+		return h, nil
+	}
+
+	return nil, mbtiles.ErrTilesetNotFound
+}
+
+// IDs returns the sorted IDs of all registered providers
+func IDs() []string {
+	providersMu.RLock()
+	ids := make([]string, 0, len(providers))
+	for id := range providers {
+		ids = append(ids, id)
+	}
+	providersMu.RUnlock()
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Load creates a Provider of all supported files in the specified directory
+// and adds them to the registry. Calling it again adds new files, replaces
+// changed ones and removes the providers of deleted files.
+func Load(path string) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	directory.Store(path)
+
+	files, err := scan(path)
+	if err != nil {
+		return err
+	}
+
+	providersMu.RLock()
+	current := make(map[string]*Handle, len(providers))
+	for id, h := range providers {
+		if h.file != "" {
+			current[id] = h
+		} else {
+			// registered providers take precedence over files
+			delete(files, id)
+		}
+	}
+	providersMu.RUnlock()
+
+	type result struct {
+		id string
+		f  fileInfo
+		h  *Handle
+	}
+
+	ch := make(chan result, 1)
+	wg := &sync.WaitGroup{}
+
+	for id, f := range files {
+		if h, ok := current[id]; ok && f.equal(h.file, h.modTime, h.size) {
+			continue
+		}
+
+		// do not retry a broken file until it changes
+		if ff, ok := failed[id]; ok && f.equal(ff.path, ff.modTime, ff.size) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(id string, f fileInfo) {
+			defer wg.Done()
+
+			p, err := f.open(f.path)
+			if err != nil {
+				logger.Errorf("Loading tileset \"%s\" failed: %s", filepath.Base(f.path), err)
+				ch <- result{id, f, nil}
+				return
+			}
+
+			h := newHandle(id, p)
+			h.file, h.modTime, h.size = f.path, f.modTime, f.size
+
+			ch <- result{id, f, h}
+		}(id, f)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	var loaded int
+	changed := make(map[string]struct{})
+	for r := range ch {
+		if r.h == nil {
+			failed[r.id] = r.f
+			err = fmt.Errorf("some tilesets could not be loaded")
+			continue
+		}
+		delete(failed, r.id)
+
+		if _, ok := current[r.id]; ok {
+			logger.Infof("Tileset \"%s\" changed, replacing it", r.id)
+		}
+
+		swap(r.id, r.h)
+		buildIndex(r.id, r.h)
+		changed[r.id] = struct{}{}
+		loaded++
+	}
+
+	for id := range current {
+		if _, ok := files[id]; !ok {
+			logger.Infof("Tileset \"%s\" removed", id)
+			swap(id, nil)
+			changed[id] = struct{}{}
+		}
+	}
+
+	validateComposites(changed)
+
+	for id := range failed {
+		if _, ok := files[id]; !ok {
+			delete(failed, id)
+		}
+	}
+	failedCount.Store(int64(len(failed)))
+
+	if loaded > 0 {
+		logger.Infof("%v tileset(s) loaded successfully", loaded)
+	}
+
+	return err
+}
+
+var (
+	reloadMu sync.Mutex
+
+	// failed holds the files that could not be loaded, guarded by reloadMu
+	failed      = map[string]fileInfo{}
+	failedCount atomic.Int64
+)
+
+// directory is the path of the last loaded directory
+var directory atomic.Value
+
+// Directory returns the path of the tileset directory or an empty string if
+// no directory has been loaded
+func Directory() string {
+	if v, ok := directory.Load().(string); ok {
+		return v
+	}
+
+	return ""
+}
+
+// Counts returns the number of registered providers and the number of files
+// that could not be loaded
+func Counts() (loaded, failedFiles int) {
+	providersMu.RLock()
+	loaded = len(providers)
+	providersMu.RUnlock()
+
+	return loaded, int(failedCount.Load())
+}
+
+type fileInfo struct {
+	path    string
+	modTime time.Time
+	size    int64
+	open    Opener
+}
+
+func (f fileInfo) equal(path string, modTime time.Time, size int64) bool {
+	return f.path == path && f.modTime.Equal(modTime) && f.size == size
+}
+
+// scan returns the supported files of the directory by tileset ID
+func scan(path string) (map[string]fileInfo, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tileset directory failed: %w", err)
+	}
+
+	files := make(map[string]fileInfo, len(entries))
+
+	for _, e := range entries {
+		name := e.Name()
+		ext := filepath.Ext(name)
+
+		open, ok := openers[ext]
+		if !ok || e.IsDir() {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			// the file has been removed in the meantime
+			continue
+		}
+
+		id := strings.TrimSuffix(name, ext)
+		if IsReserved(id) {
+			logger.Errorf("Tileset ID \"%s\" is reserved, skipping \"%s\"", id, name)
+			continue
+		}
+		if _, ok := files[id]; ok {
+			logger.Warningf("Tileset ID \"%s\" is not unique, skipping \"%s\"", id, name)
+			continue
+		}
+
+		files[id] = fileInfo{
+			path:    filepath.Join(path, name),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			open:    open,
+		}
+	}
+
+	return files, nil
+}
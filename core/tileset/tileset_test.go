@@ -0,0 +1,351 @@
+package tileset
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/pmtiles"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+)
+
+// unregister removes the providers of the IDs after the test
+func unregister(t *testing.T, ids ...string) {
+	t.Cleanup(func() {
+		for _, id := range ids {
+			Unregister(id)
+		}
+	})
+}
+
+const stubExtension = ".stub"
+
+// stubOpens counts the opened stub files by name
+var (
+	stubOpens   = map[string]int{}
+	stubOpensMu sync.Mutex
+)
+
+func opens(name string) int {
+	stubOpensMu.Lock()
+	defer stubOpensMu.Unlock()
+
+	return stubOpens[name]
+}
+
+func init() {
+	RegisterOpener(stubExtension, func(file string) (Provider, error) {
+		name := filepath.Base(file)
+
+		stubOpensMu.Lock()
+		stubOpens[name]++
+		stubOpensMu.Unlock()
+
+		if name == "broken"+stubExtension {
+			return nil, errors.New("broken file")
+		}
+		return &tilesettest.Provider{Metadata: mbtiles.Metadata{Name: name}, Format: mbtiles.PNG}, nil
+	})
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		file      string
+		supported bool
+		err       error
+	}{
+		{"dir/a.stub", true, nil},
+		{"a.mbtiles.stub", true, nil},
+		{"a.txt", false, ErrUnsupportedFile},
+		{"a", false, ErrUnsupportedFile},
+		{"stub", false, ErrUnsupportedFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := IsSupported(tt.file); got != tt.supported {
+				t.Errorf("IsSupported() = %v, want %v", got, tt.supported)
+			}
+
+			p, err := Open(tt.file)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if s, ok := p.(*tilesettest.Provider); !ok || s.Metadata.Name != filepath.Base(tt.file) {
+				t.Errorf("Open() = %#v, want the provider of the opener", p)
+			}
+		})
+	}
+
+	for _, ext := range []string{mbtiles.FileExtension, pmtiles.FileExtension} {
+		if !IsSupported("a" + ext) {
+			t.Errorf("IsSupported(%q) = false, want true", ext)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	unregister(t, "registry-a", "registry-b")
+
+	a, b := &tilesettest.Provider{}, &tilesettest.Provider{}
+	Register("registry-b", b)
+	Register("registry-a", a)
+
+	if _, err := Get("registry-missing"); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+		t.Errorf("Get() of a missing ID error = %v, want %v", err, mbtiles.ErrTilesetNotFound)
+	}
+
+	h, err := Get("registry-a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if h.Provider != a {
+		t.Errorf("Get() = %v, want the registered provider", h.Provider)
+	}
+
+	// a replaced provider is closed once it is released
+	replacement := &tilesettest.Provider{}
+	Register("registry-a", replacement)
+
+	if a.Closed() {
+		t.Error("replaced provider closed while it is in use")
+	}
+	h.Release()
+	if !a.Closed() {
+		t.Error("replaced provider not closed after release")
+	}
+
+	h2, err := Get("registry-a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer h2.Release()
+
+	if h2.Provider != replacement {
+		t.Errorf("Get() = %v, want the replacement", h2.Provider)
+	}
+	if h2.Generation() == h.Generation() {
+		t.Error("replacement has the generation of the replaced provider")
+	}
+
+	var ids []string
+	for _, id := range IDs() {
+		if id == "registry-a" || id == "registry-b" {
+			ids = append(ids, id)
+		}
+	}
+	if want := []string{"registry-a", "registry-b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("IDs() = %v, want %v", ids, want)
+	}
+
+	Unregister("registry-b")
+	if _, err := Get("registry-b"); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+		t.Errorf("Get() of an unregistered ID error = %v, want %v", err, mbtiles.ErrTilesetNotFound)
+	}
+	if !b.Closed() {
+		t.Error("unregistered provider not closed")
+	}
+}
+
+// TestRelease replaces a provider while it is used concurrently, a provider
+// must never be closed while a Handle of it is held
+func TestRelease(t *testing.T) {
+	unregister(t, "release")
+
+	Register("release", &tilesettest.Provider{})
+
+	var replaced []*tilesettest.Provider
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				h, err := Get("release")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				p := h.Provider.(*tilesettest.Provider)
+				if p.Closed() {
+					t.Error("provider closed while a Handle is held")
+				}
+				time.Sleep(10 * time.Microsecond)
+				if p.Closed() {
+					t.Error("provider closed while a Handle is held")
+				}
+
+				h.Release()
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		p := &tilesettest.Provider{}
+		replaced = append(replaced, p)
+		Register("release", p)
+	}
+
+	close(stop)
+	wg.Wait()
+
+	// all but the registered provider are closed after the last release
+	for _, p := range replaced[:len(replaced)-1] {
+		if !p.Closed() {
+			t.Fatal("replaced provider not closed after its last release")
+		}
+	}
+	if replaced[len(replaced)-1].Closed() {
+		t.Error("registered provider closed")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	unregister(t, "one", "two", "broken")
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("one"+stubExtension, "one")
+	write("two"+stubExtension, "two")
+	write("broken"+stubExtension, "broken")
+	write("ignored.txt", "ignored")
+
+	if err := Load(dir); err == nil {
+		t.Error("Load() error = nil, want an error for the broken file")
+	}
+	if loaded, failed := Counts(); loaded < 2 || failed != 1 {
+		t.Errorf("Counts() = %v, %v, want at least 2 loaded and 1 failed", loaded, failed)
+	}
+
+	for _, id := range []string{"one", "two"} {
+		h, err := Get(id)
+		if err != nil {
+			t.Fatalf("Get(%q) error = %v", id, err)
+		}
+		if h.FileSize() != int64(len(id)) {
+			t.Errorf("FileSize() = %v, want %v", h.FileSize(), len(id))
+		}
+		h.Release()
+	}
+	for _, id := range []string{"broken", "ignored"} {
+		if _, err := Get(id); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+			t.Errorf("Get(%q) error = %v, want %v", id, err, mbtiles.ErrTilesetNotFound)
+		}
+	}
+
+	get := func(id string) *Handle {
+		h, err := Get(id)
+		if err != nil {
+			t.Fatalf("Get(%q) error = %v", id, err)
+		}
+		h.Release()
+		return h
+	}
+	one, two := get("one"), get("two")
+
+	// unchanged files are not reopened, a broken file is not retried until
+	// it changes
+	brokenOpens := opens("broken" + stubExtension)
+	if err := Load(dir); err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+	if opens("broken"+stubExtension) != brokenOpens {
+		t.Error("unchanged broken file opened again")
+	}
+	if _, failed := Counts(); failed != 1 {
+		t.Errorf("%v failed files, want the unchanged broken file", failed)
+	}
+	if get("one") != one || get("two") != two {
+		t.Error("unchanged file reloaded")
+	}
+
+	// a changed file is replaced, a removed one is closed
+	write("one"+stubExtension, "changed")
+	for _, name := range []string{"two", "broken"} {
+		if err := os.Remove(filepath.Join(dir, name+stubExtension)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Load(dir); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, failed := Counts(); failed != 0 {
+		t.Errorf("%v failed files after the broken file was removed, want 0", failed)
+	}
+
+	if h := get("one"); h == one || h.FileSize() != int64(len("changed")) {
+		t.Error("changed file not reloaded")
+	}
+	if !one.Provider.(*tilesettest.Provider).Closed() {
+		t.Error("provider of a changed file not closed")
+	}
+
+	if _, err := Get("two"); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+		t.Errorf("Get() of a removed file error = %v, want %v", err, mbtiles.ErrTilesetNotFound)
+	}
+	if !two.Provider.(*tilesettest.Provider).Closed() {
+		t.Error("provider of a removed file not closed")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	dir := t.TempDir()
+	unregister(t, "reserved", "unreserved")
+
+	Reserve("reserved")
+	t.Cleanup(func() { delete(reserved, "reserved") })
+
+	for _, name := range []string{"reserved", "unreserved"} {
+		if err := os.WriteFile(filepath.Join(dir, name+stubExtension), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Load(dir); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if _, err := Get("reserved"); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+		t.Errorf("Get() of a reserved ID error = %v, want %v", err, mbtiles.ErrTilesetNotFound)
+	}
+	if opens("reserved"+stubExtension) != 0 {
+		t.Error("file with a reserved ID opened")
+	}
+
+	h, err := Get("unreserved")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	h.Release()
+
+	RegisterComposite("reserved", []string{"unreserved", "other"})
+	if _, err := Get("reserved"); !errors.Is(err, mbtiles.ErrTilesetNotFound) {
+		t.Errorf("Get() of a reserved composite error = %v, want %v", err, mbtiles.ErrTilesetNotFound)
+	}
+	if len(dependents["unreserved"]) != 0 {
+		t.Errorf("dependents = %v, want none of the reserved composite", dependents["unreserved"])
+	}
+}
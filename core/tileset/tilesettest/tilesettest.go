@@ -0,0 +1,106 @@
+// Package tilesettest provides an in-memory tile provider for tests
+package tilesettest
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+)
+
+// Provider is a tile provider of tiles and grids held in memory. The tiles
+// and grids are keyed by their TMS coordinates.
+type Provider struct {
+	Metadata        mbtiles.Metadata
+	Format          mbtiles.TileFormat
+	Compression     compression.Encoding
+	GridCompression mbtiles.TileFormat
+	Tiles           map[mbtiles.TileCoord][]byte
+	Grids           map[mbtiles.TileCoord][]byte
+	Modified        time.Time
+
+	// MetadataError is returned by GetMetadata if set
+	MetadataError error
+
+	closed atomic.Bool
+}
+
+// GetTile returns the tile or mbtiles.ErrTileNotFound
+func (p *Provider) GetTile(tc *mbtiles.TileCoord) ([]byte, error) {
+	if data, ok := p.Tiles[*tc]; ok {
+		return data, nil
+	}
+	return nil, mbtiles.ErrTileNotFound
+}
+
+// GetGrid returns the grid, mbtiles.ErrNoUTFGrid if the provider has no
+// grids or mbtiles.ErrTileNotFound
+func (p *Provider) GetGrid(tc *mbtiles.TileCoord) ([]byte, error) {
+	if p.Grids == nil {
+		return nil, mbtiles.ErrNoUTFGrid
+	}
+	if data, ok := p.Grids[*tc]; ok {
+		return data, nil
+	}
+	return nil, mbtiles.ErrTileNotFound
+}
+
+// GetMetadata returns a copy of the metadata or the MetadataError
+func (p *Provider) GetMetadata() (*mbtiles.Metadata, error) {
+	if p.MetadataError != nil {
+		return nil, p.MetadataError
+	}
+
+	md := p.Metadata
+	return &md, nil
+}
+
+// TileFormat returns the format of the tiles
+func (p *Provider) TileFormat() mbtiles.TileFormat {
+	return p.Format
+}
+
+// TileCompression returns the compression of the tiles
+func (p *Provider) TileCompression() compression.Encoding {
+	return p.Compression
+}
+
+// GridFormat returns the compression of the grids
+func (p *Provider) GridFormat() mbtiles.TileFormat {
+	return p.GridCompression
+}
+
+// ModTime returns the modification time
+func (p *Provider) ModTime() time.Time {
+	return p.Modified
+}
+
+// Close marks the provider as closed
+func (p *Provider) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+// Closed reports whether the provider has been closed
+func (p *Provider) Closed() bool {
+	return p.closed.Load()
+}
+
+// VectorTile encodes the vector tile in the given compression
+func VectorTile(tb testing.TB, t *mvt.Tile, enc compression.Encoding) []byte {
+	tb.Helper()
+
+	data, err := mvt.Encode(t)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	if data, err = compression.Encode(data, enc); err != nil {
+		tb.Fatal(err)
+	}
+
+	return data
+}
@@ -0,0 +1,37 @@
+package tileset
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/logger"
+)
+
+// Watch reloads the tilesets of the directory whenever the modification time
+// or size of a file changes and on SIGHUP. The directory is polled in the
+// given interval, an interval of zero disables polling.
+func Watch(path string, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-hup:
+			logger.Info("SIGHUP received, reloading tilesets")
+		case <-tick:
+		}
+
+		if err := Load(path); err != nil {
+			logger.Errorf("Tileset reloading error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package tms describes the WebMercatorQuad tile matrix set used by all tilesets
+package tms
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	// ID is the identifier of the WebMercatorQuad tile matrix set
+	ID = "WebMercatorQuad"
+
+	// URI is the registered URI of the WebMercatorQuad tile matrix set
+	URI = "http://www.opengis.net/def/tilematrixset/OGC/1.0/WebMercatorQuad"
+
+	// CRS is the URI of the coordinate reference system of the tile matrix set
+	CRS = "http://www.opengis.net/def/crs/EPSG/0/3857"
+
+	// WellKnownScaleSet is the URI of the well-known scale set
+	WellKnownScaleSet = "http://www.opengis.net/def/wkss/OGC/1.0/GoogleMapsCompatible"
+
+	// TileSize is the width and height of a tile in pixels
+	TileSize = 256
+
+	// MaxZoom is the highest zoom level of the tile matrix set
+	MaxZoom = 24
+
+	// Origin is the distance of the top left corner to the CRS origin in meters
+	Origin = 20037508.3427892
+
+	// pixelSize is the size of a pixel in meters according to OGC WMTS
+	pixelSize = 0.00028
+
+	// earthCircumference is the equatorial circumference in meters
+	earthCircumference = 2 * Origin
+)
+
+// MaxLatitude is the highest latitude covered by the tile matrix set
+var MaxLatitude = 2*math.Atan(math.Exp(math.Pi))*180/math.Pi - 90
+
+// TileMatrix describes the tile matrix of a zoom level
+type TileMatrix struct {
+	ID               string     `json:"id"`
+	ScaleDenominator float64    `json:"scaleDenominator"`
+	CellSize         float64    `json:"cellSize"`
+	CornerOfOrigin   string     `json:"cornerOfOrigin"`
+	PointOfOrigin    [2]float64 `json:"pointOfOrigin"`
+	TileWidth        int        `json:"tileWidth"`
+	TileHeight       int        `json:"tileHeight"`
+	MatrixWidth      uint64     `json:"matrixWidth"`
+	MatrixHeight     uint64     `json:"matrixHeight"`
+}
+
+// NewTileMatrix returns the TileMatrix of the zoom level
+func NewTileMatrix(z uint8) TileMatrix {
+	cellSize := CellSize(z)
+	n := uint64(1) << z
+
+	return TileMatrix{
+		ID:               strconv.Itoa(int(z)),
+		ScaleDenominator: cellSize / pixelSize,
+		CellSize:         cellSize,
+		CornerOfOrigin:   "topLeft",
+		PointOfOrigin:    [2]float64{-Origin, Origin},
+		TileWidth:        TileSize,
+		TileHeight:       TileSize,
+		MatrixWidth:      n,
+		MatrixHeight:     n,
+	}
+}
+
+// CellSize returns the size of a pixel in meters at the zoom level
+func CellSize(z uint8) float64 {
+	return earthCircumference / TileSize / float64(uint64(1)<<z)
+}
+
+// LonLatToTile returns the column and row of the tile containing the
+// WGS84 coordinates at the zoom level. Rows are counted from the top.
+func LonLatToTile(lon, lat float64, z uint8) (x, y uint64) {
+	fx, fy := LonLatToPixel(lon, lat, z)
+
+	last := float64(uint64(1)<<z) - 1
+
+	x = uint64(math.Max(0, math.Min(last, math.Floor(fx/TileSize))))
+	y = uint64(math.Max(0, math.Min(last, math.Floor(fy/TileSize))))
+
+	return
+}
+
+// LonLatToPixel returns the global pixel coordinates of the WGS84
+// coordinates at the zoom level, measured from the top left corner
+func LonLatToPixel(lon, lat float64, z uint8) (px, py float64) {
+	lat = math.Max(-MaxLatitude, math.Min(MaxLatitude, lat))
+
+	size := float64(TileSize) * float64(uint64(1)<<z)
+	sin := math.Sin(lat * math.Pi / 180)
+
+	px = (lon + 180) / 360 * size
+	py = (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * size
+
+	return
+}
+
+// PixelToLonLat returns the WGS84 coordinates of the global pixel
+// coordinates at the zoom level, the inverse of LonLatToPixel
+func PixelToLonLat(px, py float64, z uint8) (lon, lat float64) {
+	size := float64(TileSize) * float64(uint64(1)<<z)
+
+	lon = px/size*360 - 180
+	lat = math.Atan(math.Sinh(math.Pi*(1-2*py/size))) * 180 / math.Pi
+
+	return
+}
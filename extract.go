@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+)
+
+// extract writes the tiles of an area of an MBTiles file into a new one
+func extract(args []string) int {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	bbox := fs.String("bbox", "", "bounds of the area as west,south,east,north")
+	minZoom := fs.Uint("minzoom", 0, "lowest zoom level")
+	maxZoom := fs.Int("maxzoom", -1, "highest zoom level, the one of the input by default")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tileserver extract -bbox w,s,e,n [-minzoom n] [-maxzoom n] <input> <output>\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	bounds, err := mbtiles.ParseBounds(*bbox)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid bbox: %s\n", err)
+		return 2
+	}
+
+	in, out := fs.Arg(0), fs.Arg(1)
+	if _, err := os.Stat(out); !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Output file %s already exists\n", out)
+		return 1
+	}
+
+	ts, err := mbtiles.NewTileset(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not open %s: %s\n", in, err)
+		return 1
+	}
+	defer ts.Close()
+
+	if *maxZoom < 0 {
+		md, err := ts.GetMetadata()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read metadata: %s\n", err)
+			return 1
+		}
+		*maxZoom = md.MaxZoom
+	}
+
+	if *minZoom > 255 || *maxZoom > 255 {
+		fmt.Fprintf(os.Stderr, "Invalid zoom range %v to %v\n", *minZoom, *maxZoom)
+		return 2
+	}
+
+	opts := mbtiles.ExtractOptions{
+		Bounds:  bounds,
+		MinZoom: uint8(*minZoom),
+		MaxZoom: uint8(*maxZoom),
+	}
+
+	if err := ts.Extract(out, opts); err != nil {
+		os.Remove(out)
+		fmt.Fprintf(os.Stderr, "Extract failed: %s\n", err)
+		return 1
+	}
+
+	info, err := os.Stat(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	fmt.Printf("Extracted zoom levels %v to %v into %s (%v bytes)\n", opts.MinZoom, opts.MaxZoom, out, info.Size())
+
+	return 0
+}
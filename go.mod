@@ -3,10 +3,13 @@ module github.com/tarkov-database/tileserver
 go 1.20
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/google/logger v1.1.1
 	github.com/julienschmidt/httprouter v1.3.0
+	github.com/klauspost/compress v1.16.7
 	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/zeebo/blake3 v0.2.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
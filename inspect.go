@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+)
+
+// inspect prints the metadata, format, zoom range and tile counts of an
+// MBTiles file
+func inspect(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: tileserver inspect <file>\n")
+		return 2
+	}
+
+	ts, err := mbtiles.NewTileset(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not open %s: %s\n", args[0], err)
+		return 1
+	}
+	defer ts.Close()
+
+	md, err := ts.GetMetadata()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read metadata: %s\n", err)
+		return 1
+	}
+
+	counts, err := ts.TileCounts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not count tiles: %s\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "File:\t%s\n", ts.Filename)
+	fmt.Fprintf(w, "Name:\t%s\n", md.Name)
+	if md.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", md.Description)
+	}
+	if md.Version != "" {
+		fmt.Fprintf(w, "Version:\t%s\n", md.Version)
+	}
+	if md.Attribution != "" {
+		fmt.Fprintf(w, "Attribution:\t%s\n", md.Attribution)
+	}
+	fmt.Fprintf(w, "Type:\t%s\n", md.Type)
+	fmt.Fprintf(w, "Format:\t%s\n", ts.Format)
+	if md.Format != mbtiles.UNKNOWN && md.Format != ts.Format {
+		fmt.Fprintf(w, "Metadata format:\t%s\n", md.Format)
+	}
+	fmt.Fprintf(w, "Compression:\t%s\n", ts.Compression)
+	fmt.Fprintf(w, "Zoom:\t%v - %v\n", md.MinZoom, md.MaxZoom)
+	fmt.Fprintf(w, "Bounds:\t%v\n", md.Bounds)
+	fmt.Fprintf(w, "Center:\t%v\n", md.Center)
+	fmt.Fprintf(w, "UTFGrid:\t%v\n", ts.UTFGrid)
+	if ts.UTFGrid {
+		fmt.Fprintf(w, "UTFGrid compression:\t%s\n", ts.UTFGridCompression)
+	}
+	if md.LayerData != nil && md.LayerData.VectorLayers != nil {
+		for _, l := range *md.LayerData.VectorLayers {
+			fmt.Fprintf(w, "Vector layer:\t%s (%v fields)\n", l.ID, len(l.Fields))
+		}
+	}
+
+	zooms := make([]int64, 0, len(counts))
+	var total int64
+	for z, n := range counts {
+		zooms = append(zooms, z)
+		total += n
+	}
+	sort.Slice(zooms, func(i, j int) bool { return zooms[i] < zooms[j] })
+
+	fmt.Fprintf(w, "Tiles:\t%v\n", total)
+	for _, z := range zooms {
+		fmt.Fprintf(w, "  Zoom %v:\t%v\n", z, counts[z])
+	}
+
+	w.Flush()
+
+	return 0
+}
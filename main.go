@@ -2,33 +2,39 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"os"
-
-	"github.com/tarkov-database/tileserver/core/mbtiles"
-	"github.com/tarkov-database/tileserver/core/server"
-	"github.com/tarkov-database/tileserver/model"
-
-	"github.com/google/logger"
+	"strings"
 )
 
-func main() {
-	fmt.Printf("Starting up Tarkov Database TileServer\n\n")
-
-	defLog := logger.Init("default", true, false, io.Discard)
-	defer defLog.Close()
+const usage = `Usage: tileserver [command] [arguments]
 
-	tsDir := "./tilesets"
-	if env := os.Getenv("TILE_DIR"); len(env) > 0 {
-		tsDir = env
-	}
+Commands:
+  serve [-config file]   serve the tilesets (default)
+  inspect <file>         print the metadata and statistics of an MBTiles file
+  validate <dir>         check all tilesets of the directory for problems
+  extract -bbox w,s,e,n [-minzoom n] [-maxzoom n] <input> <output>
+                         write an area of an MBTiles file into a new one
+`
 
-	if err := mbtiles.LoadTilesets(tsDir); err != nil {
-		logger.Errorf("Tileset loading error: %v", err)
-		model.SetInitAsFailed()
+func main() {
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		logger.Errorf("HTTP server error: %s", err)
+	switch cmd {
+	case "serve":
+		serve(args)
+	case "inspect":
+		os.Exit(inspect(args))
+	case "validate":
+		os.Exit(validate(args))
+	case "extract":
+		os.Exit(extract(args))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
 	}
 }
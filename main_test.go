@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTileset creates an MBTiles file with a PNG tile at 0/0/0 and runs the
+// statements on it
+func writeTileset(t *testing.T, file string, stmts ...string) {
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmts = append([]string{
+		"CREATE TABLE metadata (name text, value text)",
+		"CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)",
+		"INSERT INTO metadata VALUES ('name', 'test'), ('format', 'png'), ('minzoom', '0'), ('maxzoom', '0')",
+		"INSERT INTO tiles VALUES (0, 0, 0, x'89504e470d0a1a0a')",
+	}, stmts...)
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.mbtiles")
+	writeTileset(t, valid)
+
+	// a negative row does not fail the tile counts
+	invalid := filepath.Join(dir, "invalid.mbtiles")
+	writeTileset(t, invalid, "INSERT INTO tiles VALUES (0, 0, -1, x'89504e470d0a1a0a')")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no file", nil, 2},
+		{"too many files", []string{valid, valid}, 2},
+		{"missing file", []string{filepath.Join(dir, "missing.mbtiles")}, 1},
+		{"valid", []string{valid}, 0},
+		{"invalid tiles", []string{invalid}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inspect(tt.args); got != tt.want {
+				t.Errorf("inspect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		stmts [][]string
+		want  int
+	}{
+		{"empty directory", nil, 0},
+		{"valid", [][]string{nil, nil}, 0},
+		{"negative row", [][]string{nil, {"INSERT INTO tiles VALUES (0, 0, -1, x'89504e470d0a1a0a')"}}, 1},
+		{"zoom out of range", [][]string{{"INSERT INTO tiles VALUES (300, 0, 0, x'89504e470d0a1a0a')"}}, 1},
+		{"missing name", [][]string{{"DELETE FROM metadata WHERE name = 'name'"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, stmts := range tt.stmts {
+				writeTileset(t, filepath.Join(dir, string(rune('a'+i))+".mbtiles"), stmts...)
+			}
+
+			// unsupported files are ignored
+			if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("tiles"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := validate([]string{dir}); got != tt.want {
+				t.Errorf("validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := validate(nil); got != 2 {
+		t.Errorf("validate() without a directory = %v, want 2", got)
+	}
+	if got := validate([]string{filepath.Join(t.TempDir(), "missing")}); got != 2 {
+		t.Errorf("validate() of a missing directory = %v, want 2", got)
+	}
+}
@@ -0,0 +1,215 @@
+// Package accesslog provides a middleware that writes an access log entry
+// of every request
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/config"
+	"github.com/tarkov-database/tileserver/middleware/recorder"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Logger is a middleware that writes an access log entry of every request
+type Logger struct {
+	format     string
+	sampleRate float64
+	trusted    []*net.IPNet
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// New creates a Logger by the given configuration and opens the log file
+func New(cfg config.AccessLog) (*Logger, error) {
+	trusted, err := cfg.TrustedNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Logger{
+		format:     cfg.Format,
+		sampleRate: cfg.SampleRate,
+		trusted:    trusted,
+		out:        os.Stdout,
+	}
+
+	if l.format != config.FormatNone && cfg.File != "" {
+		if l.out, err = openRotatingFile(cfg.File, cfg.MaxSize, cfg.MaxBackups); err != nil {
+			return nil, fmt.Errorf("could not open access log file: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// entry is a line of the access log
+type entry struct {
+	Time     string  `json:"time"`
+	Method   string  `json:"method"`
+	Path     string  `json:"path"`
+	Tileset  string  `json:"tileset,omitempty"`
+	Z        string  `json:"z,omitempty"`
+	X        string  `json:"x,omitempty"`
+	Y        string  `json:"y,omitempty"`
+	Status   int     `json:"status"`
+	Bytes    int     `json:"bytes"`
+	Duration float64 `json:"duration"`
+	ClientIP string  `json:"client_ip"`
+	Cache    string  `json:"cache,omitempty"`
+}
+
+// Handler writes an access log entry after the handle has finished. Server
+// errors are always logged, other requests according to the sample rate.
+func (l *Logger) Handler(h httprouter.Handle) httprouter.Handle {
+	if l.format == config.FormatNone {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		rw := recorder.New(w)
+		start := time.Now()
+
+		h(rw, r, ps)
+
+		status := rw.StatusCode()
+		if status < http.StatusInternalServerError && l.sampleRate < 1 && rand.Float64() >= l.sampleRate {
+			return
+		}
+
+		e := &entry{
+			Time:     start.UTC().Format(time.RFC3339Nano),
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Tileset:  firstParam(ps, "id", "layer"),
+			Z:        ps.ByName("z"),
+			X:        firstParam(ps, "x", "col"),
+			Y:        firstParam(ps, "y", "row"),
+			Status:   status,
+			Bytes:    rw.Bytes,
+			Duration: time.Since(start).Seconds(),
+			ClientIP: l.clientIP(r),
+			Cache:    strings.ToLower(rw.Header().Get("X-Cache")),
+		}
+
+		l.write(e)
+	}
+}
+
+func firstParam(ps httprouter.Params, names ...string) string {
+	for _, n := range names {
+		if v := ps.ByName(n); len(v) > 0 {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// clientIP returns the address of the client. The X-Forwarded-For header is
+// only used if the request comes from a trusted proxy, the client is the
+// last address of the chain that is not a trusted proxy.
+func (l *Logger) clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	if !l.isTrusted(remote) {
+		return remote
+	}
+
+	chain := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(chain[i])
+		if ip == "" {
+			continue
+		}
+		if !l.isTrusted(ip) {
+			return ip
+		}
+		remote = ip
+	}
+
+	return remote
+}
+
+func (l *Logger) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range l.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *Logger) write(e *entry) {
+	var buf bytes.Buffer
+
+	switch l.format {
+	case config.FormatJSON:
+		if err := json.NewEncoder(&buf).Encode(e); err != nil {
+			return
+		}
+	case config.FormatLogfmt:
+		writeLogfmt(&buf, e)
+	}
+
+	l.mu.Lock()
+	l.out.Write(buf.Bytes())
+	l.mu.Unlock()
+}
+
+func writeLogfmt(buf *bytes.Buffer, e *entry) {
+	pair := func(k, v string) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		if v == "" || strings.ContainsAny(v, " =\"\\\t\n") {
+			buf.WriteString(strconv.Quote(v))
+		} else {
+			buf.WriteString(v)
+		}
+	}
+
+	pair("time", e.Time)
+	pair("method", e.Method)
+	pair("path", e.Path)
+	if e.Tileset != "" {
+		pair("tileset", e.Tileset)
+	}
+	if e.Z != "" {
+		pair("z", e.Z)
+		pair("x", e.X)
+		pair("y", e.Y)
+	}
+	pair("status", strconv.Itoa(e.Status))
+	pair("bytes", strconv.Itoa(e.Bytes))
+	pair("duration", strconv.FormatFloat(e.Duration, 'f', 6, 64))
+	pair("client_ip", e.ClientIP)
+	if e.Cache != "" {
+		pair("cache", e.Cache)
+	}
+
+	buf.WriteByte('\n')
+}
@@ -1,57 +1,28 @@
 package cors
 
 import (
-	"fmt"
-	"log"
 	"net/http"
-	"net/url"
-	"os"
-	"strings"
+
+	"github.com/tarkov-database/tileserver/core/config"
 
 	"github.com/julienschmidt/httprouter"
 )
 
-var corsOrigins []string
-
-func init() {
-	var err error
-
-	corsOrigins, err = parseCORSOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"))
-	if err != nil {
-		log.Printf("CORS origin configuration error: %s\n", err)
-		os.Exit(2)
-	}
+// CORS is a middleware that allows cross-origin requests of the
+// configured origins
+type CORS struct {
+	origins []string
 }
 
-func parseCORSOrigins(originsStr string) ([]string, error) {
-	origins := []string{}
-
-	if originsStr != "" {
-		originsArr := strings.Split(originsStr, ",")
-		for _, origin := range originsArr {
-			origin = strings.TrimSpace(origin)
-			if origin != "" {
-				// Validate the URL
-				u, err := url.ParseRequestURI(origin)
-				if err != nil {
-					return nil, err
-				}
-				// Only allow http and https schemes
-				if u.Scheme != "http" && u.Scheme != "https" {
-					return nil, fmt.Errorf("invalid URL scheme %q in origin %q", u.Scheme, origin)
-				}
-				origins = append(origins, origin)
-			}
-		}
-	}
-
-	return origins, nil
+// New creates a CORS middleware by the given configuration
+func New(cfg config.CORS) *CORS {
+	return &CORS{origins: cfg.AllowedOrigins}
 }
 
-func Handler(h httprouter.Handle) httprouter.Handle {
+func (c *CORS) Handler(h httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		if origin := r.Header.Get("Origin"); origin != "" {
-			for _, v := range corsOrigins {
+			for _, v := range c.origins {
 				if v == origin {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
 					break
@@ -0,0 +1,151 @@
+// Package metrics provides an instrumentation middleware and exposes the
+// metrics in the Prometheus text exposition format
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tarkov-database/tileserver/middleware/recorder"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	durationBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+	queryBuckets    = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25}
+)
+
+var (
+	requests = newCounter("tileserver_http_requests_total",
+		"Number of HTTP requests.", "route", "status", "tileset")
+	requestDuration = newHistogram("tileserver_http_request_duration_seconds",
+		"Duration of HTTP requests.", durationBuckets, "route", "status", "tileset")
+	tileBytes = newCounter("tileserver_tile_bytes_total",
+		"Number of tile bytes served.", "tileset")
+	tilesNotFound = newCounter("tileserver_tiles_not_found_total",
+		"Number of requested tiles that do not exist.", "tileset", "zoom")
+	queryDuration = newHistogram("tileserver_tile_query_duration_seconds",
+		"Duration of MBTiles tile queries.", queryBuckets)
+)
+
+// State provides the state of the tilesets and the tile cache, it is set by
+// the application with SetState
+type State interface {
+	// TilesetCounts returns the number of loaded and failed tilesets
+	TilesetCounts() (loaded, failed int)
+
+	// HasTileset reports whether a tileset with the ID is registered
+	HasTileset(id string) bool
+
+	// CacheStats returns the hits, the misses and the size in bytes of the
+	// tile cache
+	CacheStats() (hits, misses uint64, size int64)
+}
+
+var state State
+
+// SetState sets the state exposed by the tileset and tile cache metrics. It
+// has to be called before the metrics are served.
+func SetState(s State) {
+	state = s
+}
+
+func init() {
+	newGaugeFunc("tileserver_tilesets_loaded", "Number of loaded tilesets.", func() float64 {
+		if state == nil {
+			return 0
+		}
+		loaded, _ := state.TilesetCounts()
+		return float64(loaded)
+	})
+	newGaugeFunc("tileserver_tilesets_failed", "Number of tilesets that could not be loaded.", func() float64 {
+		if state == nil {
+			return 0
+		}
+		_, failed := state.TilesetCounts()
+		return float64(failed)
+	})
+	newCounterFunc("tileserver_tile_cache_hits_total", "Number of tile cache hits.", func() float64 {
+		if state == nil {
+			return 0
+		}
+		hits, _, _ := state.CacheStats()
+		return float64(hits)
+	})
+	newCounterFunc("tileserver_tile_cache_misses_total", "Number of tile cache misses.", func() float64 {
+		if state == nil {
+			return 0
+		}
+		_, misses, _ := state.CacheStats()
+		return float64(misses)
+	})
+	newGaugeFunc("tileserver_tile_cache_bytes", "Size of the cached tiles in bytes.", func() float64 {
+		if state == nil {
+			return 0
+		}
+		_, _, size := state.CacheStats()
+		return float64(size)
+	})
+}
+
+// Handler instruments the handle of the given route
+func Handler(route string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		rw := recorder.New(w)
+		start := time.Now()
+
+		h(rw, r, ps)
+
+		id := tilesetLabel(ps)
+		status := strconv.Itoa(rw.StatusCode())
+
+		requests.add(1, route, status, id)
+		requestDuration.observe(time.Since(start).Seconds(), route, status, id)
+
+		if z := ps.ByName("z"); len(z) > 0 && len(id) > 0 {
+			switch rw.StatusCode() {
+			case http.StatusOK:
+				tileBytes.add(float64(rw.Bytes), id)
+			case http.StatusNoContent:
+				tilesNotFound.add(1, id, z)
+			}
+		}
+	}
+}
+
+// tilesetLabel returns the tileset ID of the path parameters if it is
+// registered, unknown IDs are omitted to limit the number of series
+func tilesetLabel(ps httprouter.Params) string {
+	id := ps.ByName("id")
+	if len(id) == 0 {
+		id = ps.ByName("layer")
+	}
+	if len(id) == 0 || state == nil || !state.HasTileset(id) {
+		return ""
+	}
+
+	return id
+}
+
+// ObserveTileQuery records the duration of a tile query
+func ObserveTileQuery(d time.Duration) {
+	queryDuration.observe(d.Seconds())
+}
+
+// MetricsGET writes all metrics in the text exposition format
+func MetricsGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	var buf bytes.Buffer
+	for _, c := range registry {
+		c.write(&buf)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is a metric family that can be written in the text exposition format
+type collector interface {
+	write(w io.Writer)
+}
+
+// registry holds the metric families in the order of exposition
+var registry []collector
+
+// series is the values of a metric with a set of label values
+type series struct {
+	labels []string
+
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// vec is a metric family with a fixed set of label names
+type vec struct {
+	name, help, typ string
+	labels          []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newVec(name, help, typ string, labels ...string) vec {
+	return vec{name: name, help: help, typ: typ, labels: labels, series: make(map[string]*series)}
+}
+
+// get returns the series of the label values, the lock must be held
+func (v *vec) get(lvs []string) *series {
+	key := strings.Join(lvs, "\xff")
+
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), lvs...)}
+		v.series[key] = s
+	}
+
+	return s
+}
+
+// sorted returns the series ordered by their label values, the lock must be held
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s := make([]*series, len(keys))
+	for i, k := range keys {
+		s[i] = v.series[k]
+	}
+
+	return s
+}
+
+func (v *vec) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.typ)
+}
+
+// counterVec is a monotonically increasing value
+type counterVec struct{ vec }
+
+func newCounter(name, help string, labels ...string) *counterVec {
+	c := &counterVec{newVec(name, help, "counter", labels...)}
+	registry = append(registry, c)
+	return c
+}
+
+func (c *counterVec) add(v float64, lvs ...string) {
+	c.mu.Lock()
+	c.get(lvs).value += v
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w)
+	for _, s := range c.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelString(c.labels, s.labels), formatFloat(s.value))
+	}
+}
+
+// histogramVec counts observations in cumulative buckets
+type histogramVec struct {
+	vec
+	bounds []float64
+}
+
+func newHistogram(name, help string, bounds []float64, labels ...string) *histogramVec {
+	h := &histogramVec{newVec(name, help, "histogram", labels...), bounds}
+	registry = append(registry, h)
+	return h
+}
+
+func (h *histogramVec) observe(v float64, lvs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(lvs)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(h.bounds))
+	}
+
+	for i, b := range h.bounds {
+		if v <= b {
+			s.buckets[i]++
+		}
+	}
+	s.value += v
+	s.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w)
+
+	names := append(append([]string(nil), h.labels...), "le")
+	for _, s := range h.sorted() {
+		values := append(append([]string(nil), s.labels...), "")
+		for i, b := range h.bounds {
+			values[len(values)-1] = formatFloat(b)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(names, values), s.buckets[i])
+		}
+		values[len(values)-1] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(names, values), s.count)
+
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, s.labels), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelString(h.labels, s.labels), s.count)
+	}
+}
+
+// valueFunc is a metric without labels whose value is read on exposition
+type valueFunc struct {
+	name, help, typ string
+	fn              func() float64
+}
+
+func newGaugeFunc(name, help string, fn func() float64) {
+	registry = append(registry, &valueFunc{name, help, "gauge", fn})
+}
+
+func newCounterFunc(name, help string, fn func() float64) {
+	registry = append(registry, &valueFunc{name, help, "counter", fn})
+}
+
+func (f *valueFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", f.name, f.help, f.name, f.typ, f.name, formatFloat(f.fn()))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelString(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, n, labelEscaper.Replace(values[i]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,45 @@
+// Package recorder provides a response writer that records the status and
+// size of a response for middlewares
+package recorder
+
+import "net/http"
+
+// Writer records the status code and the number of written body bytes
+type Writer struct {
+	http.ResponseWriter
+
+	Status int
+	Bytes  int
+}
+
+// New wraps the ResponseWriter
+func New(w http.ResponseWriter) *Writer {
+	return &Writer{ResponseWriter: w}
+}
+
+// WriteHeader records the status code and sends the header
+func (w *Writer) WriteHeader(status int) {
+	if w.Status == 0 {
+		w.Status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes and writes them
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += n
+	return n, err
+}
+
+// StatusCode returns the recorded status code, a handler that has not
+// written anything responds with 200
+func (w *Writer) StatusCode() int {
+	if w.Status == 0 {
+		return http.StatusOK
+	}
+	return w.Status
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/tarkov-database/tileserver/core/cache"
+	"github.com/tarkov-database/tileserver/core/tileset"
+)
+
+// tileCache is disabled until InitTileCache is called
+var tileCache = cache.New(0)
+
+// InitTileCache enables the tile cache with the given size in bytes. The tiles
+// of a tileset are invalidated when it is replaced or removed.
+func InitTileCache(size int64) {
+	tileCache = cache.New(size)
+	tileset.OnChange(tileCache.Invalidate)
+}
+
+// GetCacheStats returns the hit and miss counters and the usage of the tile cache
+func GetCacheStats() cache.Stats {
+	return tileCache.Stats()
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+var (
+	ErrBusy     = errors.New("too many concurrent requests")
+	ErrTooLarge = errors.New("entity too large")
+)
+
+// maxConcurrentExtracts limits the extracts written at the same time
+const maxConcurrentExtracts = 2
+
+var (
+	// extractMaxSize is the size limit of the tile data of an extract
+	extractMaxSize int64
+
+	extractSlots = make(chan struct{}, maxConcurrentExtracts)
+)
+
+// SetExtractMaxSize sets the size limit of the tile data of an extract
+func SetExtractMaxSize(size int64) {
+	extractMaxSize = size
+}
+
+// Extract is a temporary MBTiles file of an area of a tileset. It must be
+// closed after use, which removes the file.
+type Extract struct {
+	*os.File
+
+	// Name is the file name of the extract for downloads
+	Name     string
+	Modified time.Time
+}
+
+// Close closes and removes the file of the extract
+func (e *Extract) Close() error {
+	e.File.Close()
+	return os.Remove(e.File.Name())
+}
+
+// ParseExtractOptions parses the options of an extract from the query
+// parameters bbox, minzoom and maxzoom
+func ParseExtractOptions(q url.Values) (*mbtiles.ExtractOptions, error) {
+	opts := &mbtiles.ExtractOptions{}
+
+	var err error
+	if opts.Bounds, err = mbtiles.ParseBounds(q.Get("bbox")); err != nil {
+		return nil, fmt.Errorf("%w: invalid bbox: %s", ErrBadInput, err)
+	}
+
+	zoom := func(name string) (uint8, error) {
+		z, err := strconv.ParseUint(q.Get(name), 10, 8)
+		if err != nil || z > tms.MaxZoom {
+			return 0, fmt.Errorf("%w: %s must be an integer between 0 and %v", ErrBadInput, name, tms.MaxZoom)
+		}
+		return uint8(z), nil
+	}
+
+	if opts.MinZoom, err = zoom("minzoom"); err != nil {
+		return nil, err
+	}
+	if opts.MaxZoom, err = zoom("maxzoom"); err != nil {
+		return nil, err
+	}
+	if opts.MinZoom > opts.MaxZoom {
+		return nil, fmt.Errorf("%w: minzoom must not be greater than maxzoom", ErrBadInput)
+	}
+
+	return opts, nil
+}
+
+// ExtractTileset writes the tiles of the tileset within the bounds and zoom
+// range of the options into a temporary MBTiles file. The size limit of the
+// options is replaced by the configured one.
+func ExtractTileset(id string, opts *mbtiles.ExtractOptions) (*Extract, error) {
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
+
+	ex, ok := ts.Provider.(tileset.Extractor)
+	if !ok {
+		return nil, fmt.Errorf("%w: tileset %q does not support extracts", ErrBadInput, id)
+	}
+
+	select {
+	case extractSlots <- struct{}{}:
+		defer func() { <-extractSlots }()
+	default:
+		return nil, ErrBusy
+	}
+
+	f, err := os.CreateTemp("", "tileserver-extract-*"+mbtiles.FileExtension)
+	if err != nil {
+		return nil, err
+	}
+	name := f.Name()
+	f.Close()
+
+	o := *opts
+	o.MaxSize = extractMaxSize
+
+	if err = ex.Extract(name, o); err != nil {
+		os.Remove(name)
+		if errors.Is(err, mbtiles.ErrExtractTooLarge) {
+			return nil, fmt.Errorf("%w: the extract exceeds the size limit of %v bytes", ErrTooLarge, extractMaxSize)
+		}
+		return nil, err
+	}
+
+	if f, err = os.Open(name); err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+
+	e := &Extract{
+		File:     f,
+		Name:     id + "-extract" + mbtiles.FileExtension,
+		Modified: ts.ModTime(),
+	}
+
+	return e, nil
+}
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+// GeoJSON object types
+const (
+	geoJSONFeatureCollection = "FeatureCollection"
+	geoJSONFeature           = "Feature"
+)
+
+// GeoJSONFeatureCollection is a GeoJSON feature collection
+type GeoJSONFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []*GeoJSONFeature `json:"features"`
+}
+
+// layerProperty is the property holding the name of the vector tile layer of
+// a feature. It replaces a property of the feature with the same key.
+const layerProperty = "layer"
+
+// GeoJSONFeature is a GeoJSON feature of a vector tile layer
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         *uint64                `json:"id,omitempty"`
+	Geometry   *GeoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry is a GeoJSON geometry
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// projection transforms the coordinates of a layer into GeoJSON positions
+type projection func(mvt.Coord) [2]float64
+
+// lonLatProjection returns the projection of the coordinates of a layer with
+// the extent into WGS84. The row y of the tile is counted from the top.
+func lonLatProjection(z uint8, x, y uint64, extent uint32) projection {
+	scale := float64(tms.TileSize) / float64(extent)
+	ox, oy := float64(x*tms.TileSize), float64(y*tms.TileSize)
+
+	return func(c mvt.Coord) [2]float64 {
+		lng, lat := tms.PixelToLonLat(ox+c.X*scale, oy+c.Y*scale, z)
+		return [2]float64{roundCoord(lng), roundCoord(lat)}
+	}
+}
+
+// layerExtent returns the extent of the layer, an invalid zero extent falls
+// back to mvt.DefaultExtent like overzooming does
+func layerExtent(l *mvt.Layer) uint32 {
+	if l.Extent == 0 {
+		return mvt.DefaultExtent
+	}
+
+	return l.Extent
+}
+
+// tileProjection keeps the coordinates of a layer
+func tileProjection(c mvt.Coord) [2]float64 {
+	return [2]float64{c.X, c.Y}
+}
+
+// TileGeoJSON converts the vector tile with the coordinates into a GeoJSON
+// feature collection. The positions are in WGS84 or, if pixels is true, in
+// the coordinates of the tile grid ranging from 0 to the extent of a layer.
+// The layer of a feature is its property layer.
+func TileGeoJSON(t *Tile, tc *mbtiles.TileCoord, pixels bool) (*GeoJSONFeatureCollection, error) {
+	if t.Format != mbtiles.PBF {
+		return nil, fmt.Errorf("%w: only vector tiles can be converted into GeoJSON", ErrBadInput)
+	}
+
+	raw, err := compression.Decode(t.Data, t.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	vt, err := mvt.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode vector tile: %w", err)
+	}
+
+	// the row of the tile coordinates is counted from the bottom
+	y := (uint64(1) << tc.Z) - 1 - tc.Y
+
+	fc := newFeatureCollection()
+	for _, l := range vt.Layers {
+		project := tileProjection
+		if !pixels {
+			project = lonLatProjection(tc.Z, tc.X, y, layerExtent(l))
+		}
+
+		for _, f := range l.Features {
+			if feature := newGeoJSONFeature(l, f, project); feature != nil {
+				fc.Features = append(fc.Features, feature)
+			}
+		}
+	}
+
+	return fc, nil
+}
+
+func newFeatureCollection() *GeoJSONFeatureCollection {
+	return &GeoJSONFeatureCollection{
+		Type:     geoJSONFeatureCollection,
+		Features: make([]*GeoJSONFeature, 0),
+	}
+}
+
+// newGeoJSONFeature converts the feature of the layer, it returns nil if the
+// feature has no valid geometry. The layer name is added to the properties,
+// a layer property of the feature itself is overwritten.
+func newGeoJSONFeature(l *mvt.Layer, f *mvt.Feature, project projection) *GeoJSONFeature {
+	parts, err := f.DecodeGeometry()
+	if err != nil {
+		return nil
+	}
+
+	g := newGeoJSONGeometry(f.Type, parts, project)
+	if g == nil {
+		return nil
+	}
+
+	feature := &GeoJSONFeature{
+		Type:       geoJSONFeature,
+		Geometry:   g,
+		Properties: l.Properties(f),
+	}
+	feature.Properties[layerProperty] = l.Name
+
+	if f.HasID {
+		id := f.ID
+		feature.ID = &id
+	}
+
+	return feature
+}
+
+func newGeoJSONGeometry(t mvt.GeomType, parts [][]mvt.Coord, project projection) *GeoJSONGeometry {
+	line := func(part []mvt.Coord) [][2]float64 {
+		coords := make([][2]float64, len(part))
+		for i, c := range part {
+			coords[i] = project(c)
+		}
+		return coords
+	}
+
+	// rings are closed by repeating the first position
+	polygon := func(rings [][]mvt.Coord) [][][2]float64 {
+		coords := make([][][2]float64, len(rings))
+		for i, ring := range rings {
+			coords[i] = append(line(ring), project(ring[0]))
+		}
+		return coords
+	}
+
+	switch t {
+	case mvt.Point:
+		var points [][2]float64
+		for _, part := range parts {
+			points = append(points, line(part)...)
+		}
+
+		switch len(points) {
+		case 0:
+			return nil
+		case 1:
+			return &GeoJSONGeometry{Type: "Point", Coordinates: points[0]}
+		default:
+			return &GeoJSONGeometry{Type: "MultiPoint", Coordinates: points}
+		}
+	case mvt.LineString:
+		var lines [][][2]float64
+		for _, part := range parts {
+			if len(part) > 1 {
+				lines = append(lines, line(part))
+			}
+		}
+
+		switch len(lines) {
+		case 0:
+			return nil
+		case 1:
+			return &GeoJSONGeometry{Type: "LineString", Coordinates: lines[0]}
+		default:
+			return &GeoJSONGeometry{Type: "MultiLineString", Coordinates: lines}
+		}
+	case mvt.Polygon:
+		var polygons [][][][2]float64
+		for _, p := range mvt.Polygons(parts) {
+			polygons = append(polygons, polygon(p))
+		}
+
+		switch len(polygons) {
+		case 0:
+			return nil
+		case 1:
+			return &GeoJSONGeometry{Type: "Polygon", Coordinates: polygons[0]}
+		default:
+			return &GeoJSONGeometry{Type: "MultiPolygon", Coordinates: polygons}
+		}
+	default:
+		return nil
+	}
+}
+
+// roundCoord rounds a WGS84 coordinate to 7 decimal places, about 1 cm
+func roundCoord(v float64) float64 {
+	return math.Round(v*1e7) / 1e7
+}
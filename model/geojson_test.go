@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+)
+
+// geoJSONTile returns a vector tile with a layer of points with an extent of
+// 4096 and a layer of polygons and lines with an extent of 512
+func geoJSONTile(t *testing.T) *Tile {
+	feature := func(typ mvt.GeomType, tags []uint32, parts ...[]mvt.Coord) *mvt.Feature {
+		f := &mvt.Feature{Type: typ, Tags: tags}
+		f.EncodeGeometry(parts)
+		return f
+	}
+
+	center := feature(mvt.Point, []uint32{0, 0}, []mvt.Coord{{X: 2048, Y: 2048}})
+	center.ID, center.HasID = 7, true
+
+	vt := &mvt.Tile{Layers: []*mvt.Layer{
+		{
+			Version: 2,
+			Name:    "points",
+			Extent:  4096,
+			Keys:    []string{"name"},
+			Values:  []mvt.Value{mvt.NewValue("center"), mvt.NewValue("corners")},
+			Features: []*mvt.Feature{
+				center,
+				feature(mvt.Point, []uint32{0, 1}, []mvt.Coord{{X: 0, Y: 0}, {X: 4096, Y: 4096}}),
+			},
+		},
+		{
+			Version: 2,
+			Name:    "areas",
+			Extent:  512,
+			Features: []*mvt.Feature{
+				// an exterior ring with a hole
+				feature(mvt.Polygon, nil,
+					[]mvt.Coord{{X: 0, Y: 0}, {X: 256, Y: 0}, {X: 256, Y: 256}, {X: 0, Y: 256}},
+					[]mvt.Coord{{X: 64, Y: 64}, {X: 64, Y: 192}, {X: 192, Y: 192}, {X: 192, Y: 64}},
+				),
+				// two exterior rings
+				feature(mvt.Polygon, nil,
+					[]mvt.Coord{{X: 0, Y: 0}, {X: 8, Y: 0}, {X: 8, Y: 8}},
+					[]mvt.Coord{{X: 16, Y: 16}, {X: 24, Y: 16}, {X: 24, Y: 24}},
+				),
+				feature(mvt.LineString, nil, []mvt.Coord{{X: 0, Y: 512}, {X: 512, Y: 0}}),
+			},
+		},
+	}}
+
+	return &Tile{
+		Data:     tilesettest.VectorTile(t, vt, compression.Gzip),
+		Format:   mbtiles.PBF,
+		Encoding: compression.Gzip,
+	}
+}
+
+func TestTileGeoJSON(t *testing.T) {
+	tile := geoJSONTile(t)
+
+	tests := []struct {
+		name   string
+		tc     mbtiles.TileCoord
+		pixels bool
+		want   []string
+	}{
+		{
+			// the TMS row 1 is the northern row, the tile covers 0 to 180
+			// degrees of longitude and 0 to 85.05 degrees of latitude
+			name: "wgs84 north east",
+			tc:   mbtiles.TileCoord{Z: 1, X: 1, Y: 1},
+			want: []string{
+				`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[90,66.5132604]},"properties":{"layer":"points","name":"center"}}`,
+				`{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[0,85.0511288],[180,0]]},"properties":{"layer":"points","name":"corners"}}`,
+				`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[` +
+					`[[0,85.0511288],[90,85.0511288],[90,66.5132604],[0,66.5132604],[0,85.0511288]],` +
+					`[[22.5,82.676285],[22.5,74.0195433],[67.5,74.0195433],[67.5,82.676285],[22.5,82.676285]]]},"properties":{"layer":"areas"}}`,
+				`{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[` +
+					`[[[0,85.0511288],[2.8125,85.0511288],[2.8125,84.8024737],[0,85.0511288]]],` +
+					`[[[5.625,84.5413611],[8.4375,84.5413611],[8.4375,84.2671724],[5.625,84.5413611]]]]},"properties":{"layer":"areas"}}`,
+				`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[180,85.0511288]]},"properties":{"layer":"areas"}}`,
+			},
+		},
+		{
+			// the TMS row 0 is the southern row
+			name: "wgs84 south west",
+			tc:   mbtiles.TileCoord{Z: 1, X: 0, Y: 0},
+			want: []string{
+				`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[-90,-66.5132604]},"properties":{"layer":"points","name":"center"}}`,
+				`{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[-180,0],[0,-85.0511288]]},"properties":{"layer":"points","name":"corners"}}`,
+			},
+		},
+		{
+			name:   "pixels",
+			tc:     mbtiles.TileCoord{Z: 1, X: 1, Y: 1},
+			pixels: true,
+			want: []string{
+				`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[2048,2048]},"properties":{"layer":"points","name":"center"}}`,
+				`{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[0,0],[4096,4096]]},"properties":{"layer":"points","name":"corners"}}`,
+				`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[` +
+					`[[0,0],[256,0],[256,256],[0,256],[0,0]],` +
+					`[[64,64],[64,192],[192,192],[192,64],[64,64]]]},"properties":{"layer":"areas"}}`,
+				`{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[` +
+					`[[[0,0],[8,0],[8,8],[0,0]]],` +
+					`[[[16,16],[24,16],[24,24],[16,16]]]]},"properties":{"layer":"areas"}}`,
+				`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,512],[512,0]]},"properties":{"layer":"areas"}}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := tt.tc
+			fc, err := TileGeoJSON(tile, &tc, tt.pixels)
+			if err != nil {
+				t.Fatalf("TileGeoJSON() error = %v", err)
+			}
+			if fc.Type != "FeatureCollection" || len(fc.Features) < len(tt.want) {
+				t.Fatalf("TileGeoJSON() = %v with %v features, want at least %v", fc.Type, len(fc.Features), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got, err := json.Marshal(fc.Features[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("feature %v =\n%s\nwant\n%s", i, got, want)
+				}
+			}
+		})
+	}
+
+	if _, err := TileGeoJSON(&Tile{Format: mbtiles.PNG}, &mbtiles.TileCoord{}, false); !errors.Is(err, ErrBadInput) {
+		t.Errorf("TileGeoJSON() of a raster tile error = %v, want %v", err, ErrBadInput)
+	}
+}
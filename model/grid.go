@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+
+	"github.com/zeebo/blake3"
+)
+
+// callbackParam is the query parameter to request UTF grids as JSONP
+const callbackParam = "callback"
+
+// maxCallbackLength limits the length of JSONP callbacks
+const maxCallbackLength = 128
+
+// callbackPattern matches JavaScript identifiers and property paths, which
+// are the only callbacks allowed to prevent script injection
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+// WrapJSONP returns the UTF grid wrapped in the JavaScript callback. The
+// wrapped grid is not compressed and its hash is derived from the hash of
+// the grid and the callback.
+func WrapJSONP(t *Tile, callback string) (*Tile, error) {
+	if len(callback) > maxCallbackLength || !callbackPattern.MatchString(callback) {
+		return nil, fmt.Errorf("%w: invalid callback %q", ErrBadInput, callback)
+	}
+
+	h := blake3.New()
+	h.Write(t.Hash[:])
+	h.Write([]byte(callbackParam + "=" + callback))
+
+	tile := *t
+	tile.Hash = [32]byte(h.Sum(nil))
+	tile.Encoding = compression.Identity
+	tile.Callback = callback
+
+	key := compression.VariantKey{Hash: tile.Hash, Encoding: tile.Encoding}
+	if data, ok := variants.Get(key); ok {
+		tile.Data = data
+		return &tile, nil
+	}
+
+	raw, err := compression.Decode(t.Data, t.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(callback)+len(raw)+3)
+	data = append(data, callback...)
+	data = append(data, '(')
+	data = append(data, raw...)
+	data = append(data, ");"...)
+
+	tile.Data = data
+	variants.Add(key, tile.Data)
+
+	return &tile, nil
+}
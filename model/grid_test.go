@@ -0,0 +1,76 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+)
+
+func TestWrapJSONP(t *testing.T) {
+	const grid = `{"grid":[" !","! "],"keys":["","1"]}`
+
+	data, err := compression.Encode([]byte(grid), compression.Gzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tile := &Tile{Data: data, Format: mbtiles.JSON, Encoding: compression.Gzip, Hash: [32]byte{1}}
+
+	tests := []struct {
+		callback string
+		err      error
+	}{
+		{"grid", nil},
+		{"$jsonp_1", nil},
+		{"window.grids.customs", nil},
+		{"", ErrBadInput},
+		{"1grid", ErrBadInput},
+		{"grid()", ErrBadInput},
+		{"alert(1);grid", ErrBadInput},
+		{"grids.", ErrBadInput},
+		{"grids..customs", ErrBadInput},
+		{"grids['customs']", ErrBadInput},
+		{"grid\n", ErrBadInput},
+		{strings.Repeat("a", maxCallbackLength+1), ErrBadInput},
+	}
+
+	hashes := make(map[[32]byte]string)
+	for _, tt := range tests {
+		t.Run(tt.callback, func(t *testing.T) {
+			got, err := WrapJSONP(tile, tt.callback)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("WrapJSONP() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if want := tt.callback + "(" + grid + ");"; string(got.Data) != want {
+				t.Errorf("Data = %s, want %s", got.Data, want)
+			}
+			if got.Encoding != compression.Identity || got.Callback != tt.callback {
+				t.Errorf("Encoding, Callback = %v, %q, want %v, %q", got.Encoding, got.Callback, compression.Identity, tt.callback)
+			}
+
+			if got.Hash == tile.Hash {
+				t.Error("Hash is the hash of the grid")
+			}
+			if other, ok := hashes[got.Hash]; ok {
+				t.Errorf("Hash is the hash of callback %q", other)
+			}
+			hashes[got.Hash] = tt.callback
+
+			// the wrapped grid is served from the variant cache
+			again, err := WrapJSONP(tile, tt.callback)
+			if err != nil || string(again.Data) != string(got.Data) || again.Hash != got.Hash {
+				t.Errorf("second WrapJSONP() = %s %v, want %s", again.Data, err, got.Data)
+			}
+		})
+	}
+
+	if tile.Encoding != compression.Gzip || tile.Callback != "" {
+		t.Error("WrapJSONP() modified the grid")
+	}
+}
@@ -1,7 +1,31 @@
 package model
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/cache"
+	"github.com/tarkov-database/tileserver/core/tileset"
+)
+
 var initFailure bool
 
+var startTime = time.Now()
+
+const (
+	// checkTimeout is the maximum duration of a readiness check
+	checkTimeout = 2 * time.Second
+
+	// slowCheck is the duration after which a check is reported as warning
+	slowCheck = 500 * time.Millisecond
+)
+
 // Status represents the status code of a service
 type Status int
 
@@ -16,15 +40,32 @@ const (
 	Failure
 )
 
+var statusStrings = [...]string{
+	"ok",
+	"warning",
+	"failure",
+}
+
+// String returns a string representing the Status
+func (s Status) String() string {
+	return statusStrings[s]
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
 // Health represents the object of the health root endpoint
 type Health struct {
-	OK bool `json:"ok"`
+	OK    bool        `json:"ok"`
+	Cache cache.Stats `json:"cache"`
 }
 
 // GetHealth performs a self-check and returns the result
 func GetHealth() *Health {
 	health := &Health{
-		OK: !initFailure,
+		OK:    !initFailure,
+		Cache: GetCacheStats(),
 	}
 
 	return health
@@ -33,3 +74,142 @@ func GetHealth() *Health {
 func SetInitAsFailed() {
 	initFailure = true
 }
+
+// Liveness represents the result of the liveness check
+type Liveness struct {
+	Status Status  `json:"status"`
+	Uptime float64 `json:"uptime"`
+}
+
+// GetLiveness returns the liveness of the service, which is OK as long as
+// it is able to respond
+func GetLiveness() *Liveness {
+	return &Liveness{
+		Status: OK,
+		Uptime: time.Since(startTime).Seconds(),
+	}
+}
+
+// Check represents the result of a single readiness check
+type Check struct {
+	Name     string  `json:"name"`
+	Status   Status  `json:"status"`
+	Reason   string  `json:"reason,omitempty"`
+	Duration float64 `json:"duration_ms"`
+}
+
+// Readiness represents the result of the readiness checks, the status is
+// the worst status of all checks
+type Readiness struct {
+	Status   Status  `json:"status"`
+	Checks   []Check `json:"checks"`
+	Tilesets []Check `json:"tilesets"`
+}
+
+// GetReadiness checks the tile directory and pings all tilesets
+func GetReadiness(ctx context.Context) *Readiness {
+	rd := &Readiness{
+		Checks: []Check{
+			runCheck(ctx, "directory", checkDirectory),
+			runCheck(ctx, "tilesets", checkTilesets),
+		},
+	}
+
+	ids := tileset.IDs()
+	rd.Tilesets = make([]Check, len(ids))
+
+	wg := &sync.WaitGroup{}
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			rd.Tilesets[i] = runCheck(ctx, id, func(ctx context.Context) (Status, string) {
+				return pingTileset(ctx, id)
+			})
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, checks := range [][]Check{rd.Checks, rd.Tilesets} {
+		for _, c := range checks {
+			if c.Status > rd.Status {
+				rd.Status = c.Status
+			}
+		}
+	}
+
+	return rd
+}
+
+type checkFunc func(ctx context.Context) (Status, string)
+
+func runCheck(ctx context.Context, name string, fn checkFunc) Check {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	status, reason := fn(ctx)
+	d := time.Since(start)
+
+	if status == OK && d > slowCheck {
+		status, reason = Warning, fmt.Sprintf("check took longer than %s", slowCheck)
+	}
+
+	return Check{
+		Name:     name,
+		Status:   status,
+		Reason:   reason,
+		Duration: float64(d.Microseconds()) / 1000,
+	}
+}
+
+func checkDirectory(_ context.Context) (Status, string) {
+	dir := tileset.Directory()
+	if dir == "" {
+		return Failure, "no tileset directory loaded"
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return Failure, err.Error()
+	}
+	defer f.Close()
+
+	if _, err := f.ReadDir(1); err != nil && !errors.Is(err, io.EOF) {
+		return Failure, err.Error()
+	}
+
+	return OK, ""
+}
+
+func checkTilesets(_ context.Context) (Status, string) {
+	loaded, failed := tileset.Counts()
+
+	switch {
+	case loaded == 0:
+		return Failure, "no tilesets loaded"
+	case failed > 0:
+		return Warning, fmt.Sprintf("%v tileset(s) could not be loaded", failed)
+	default:
+		return OK, ""
+	}
+}
+
+func pingTileset(ctx context.Context, id string) (Status, string) {
+	ts, err := tileset.Get(id)
+	if err != nil {
+		return Warning, "tileset has been removed"
+	}
+	defer ts.Release()
+
+	p, ok := ts.Provider.(tileset.Pinger)
+	if !ok {
+		return OK, ""
+	}
+
+	if err := p.Ping(ctx); err != nil {
+		return Failure, err.Error()
+	}
+
+	return OK, ""
+}
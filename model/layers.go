@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+
+	"github.com/zeebo/blake3"
+)
+
+// layersParam is the query parameter to filter the layers of vector tiles
+const layersParam = "layers"
+
+// ParseLayers returns the sorted and unique layer names of the comma
+// separated list
+func ParseLayers(s string) []string {
+	set := make(map[string]struct{})
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = struct{}{}
+		}
+	}
+
+	layers := make([]string, 0, len(set))
+	for v := range set {
+		layers = append(layers, v)
+	}
+	sort.Strings(layers)
+
+	return layers
+}
+
+// LayersHash returns the hash of a vector tile filtered by the given layers
+func LayersHash(hash [32]byte, layers []string) [32]byte {
+	h := blake3.New()
+	h.Write(hash[:])
+	h.Write([]byte(layersParam + "=" + strings.Join(layers, ",")))
+
+	return [32]byte(h.Sum(nil))
+}
+
+// FilterLayers returns the vector tile with only the given layers. The
+// filtered tile is stored in the original encoding and its hash is derived
+// from the hash of the tile and the layers.
+func FilterLayers(t *Tile, layers []string) (*Tile, error) {
+	if t.Format != mbtiles.PBF {
+		return nil, fmt.Errorf("%w: layers can only be filtered in vector tiles", ErrBadInput)
+	}
+
+	tile := *t
+	tile.Hash = LayersHash(t.Hash, layers)
+
+	key := compression.VariantKey{Hash: tile.Hash, Encoding: tile.Encoding}
+	if data, ok := variants.Get(key); ok {
+		tile.Data = data
+		return &tile, nil
+	}
+
+	raw, err := compression.Decode(t.Data, t.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	vt, err := mvt.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode vector tile: %w", err)
+	}
+
+	filtered := vt.Layers[:0]
+	for _, l := range vt.Layers {
+		if i := sort.SearchStrings(layers, l.Name); i < len(layers) && layers[i] == l.Name {
+			filtered = append(filtered, l)
+		}
+	}
+	vt.Layers = filtered
+
+	if raw, err = mvt.Encode(vt); err != nil {
+		return nil, err
+	}
+
+	if tile.Data, err = compression.Encode(raw, tile.Encoding); err != nil {
+		return nil, err
+	}
+
+	variants.Add(key, tile.Data)
+
+	return &tile, nil
+}
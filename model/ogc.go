@@ -0,0 +1,369 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/core/tms"
+
+	"github.com/google/logger"
+)
+
+const (
+	ogcTitle       = "Tarkov Database TileServer"
+	ogcDescription = "Access to the tilesets via OGC API - Tiles"
+
+	crsWGS84 = "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
+
+	relSelf          = "self"
+	relConformance   = "http://www.opengis.net/def/rel/ogc/1.0/conformance"
+	relData          = "http://www.opengis.net/def/rel/ogc/1.0/data"
+	relTilingSchemes = "http://www.opengis.net/def/rel/ogc/1.0/tiling-schemes"
+	relTilesets      = "http://www.opengis.net/def/rel/ogc/1.0/tilesets-"
+	relTilingScheme  = "http://www.opengis.net/def/rel/ogc/1.0/tiling-scheme"
+	relItem          = "item"
+
+	contentTypeJSON = "application/json"
+)
+
+var conformanceClasses = []string{
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/json",
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/landing-page",
+	"http://www.opengis.net/spec/ogcapi-common-2/1.0/conf/collections",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/tileset",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/jpeg",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/png",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/mvt",
+	"http://www.opengis.net/spec/tms/2.0/conf/tilematrixset",
+	"http://www.opengis.net/spec/tms/2.0/conf/json-tilematrixset",
+}
+
+// Link represents a link of the OGC API
+type Link struct {
+	Href      string `json:"href"`
+	Rel       string `json:"rel"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+// LandingPage represents the root resource of the OGC API
+type LandingPage struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Links       []Link `json:"links"`
+}
+
+// GetLandingPage returns the LandingPage of the OGC API at the given URL
+func GetLandingPage(base *url.URL) *LandingPage {
+	return &LandingPage{
+		Title:       ogcTitle,
+		Description: ogcDescription,
+		Links: []Link{
+			{Href: base.String(), Rel: relSelf, Type: contentTypeJSON, Title: "This document"},
+			{Href: resolve(base, "conformance"), Rel: relConformance, Type: contentTypeJSON, Title: "Conformance declaration"},
+			{Href: resolve(base, "collections"), Rel: relData, Type: contentTypeJSON, Title: "Collections"},
+			{Href: resolve(base, "tileMatrixSets"), Rel: relTilingSchemes, Type: contentTypeJSON, Title: "Tile matrix sets"},
+		},
+	}
+}
+
+// Conformance represents the conformance declaration of the OGC API
+type Conformance struct {
+	ConformsTo []string `json:"conformsTo"`
+}
+
+// GetConformance returns the conformance declaration
+func GetConformance() *Conformance {
+	return &Conformance{ConformsTo: conformanceClasses}
+}
+
+// Extent represents the spatial extent of a collection
+type Extent struct {
+	Spatial struct {
+		BBox [][4]float64 `json:"bbox"`
+		CRS  string       `json:"crs"`
+	} `json:"spatial"`
+}
+
+// Collection represents a tileset as collection of the OGC API
+type Collection struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Attribution string  `json:"attribution,omitempty"`
+	Extent      *Extent `json:"extent,omitempty"`
+	DataType    string  `json:"dataType"`
+	Links       []Link  `json:"links"`
+}
+
+// Collections represents the collections of the OGC API
+type Collections struct {
+	Links       []Link        `json:"links"`
+	Collections []*Collection `json:"collections"`
+}
+
+// GetCollections returns all tilesets as Collections
+func GetCollections(base *url.URL) (*Collections, error) {
+	c := &Collections{
+		Links: []Link{
+			{Href: resolve(base, "collections"), Rel: relSelf, Type: contentTypeJSON},
+		},
+		Collections: make([]*Collection, 0),
+	}
+
+	for _, id := range tileset.IDs() {
+		col, err := GetCollection(id, base)
+		if errors.Is(err, ErrNoEntity) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			logger.Warningf("Could not list tileset \"%s\" as collection: %s", id, err)
+			continue
+		}
+
+		c.Collections = append(c.Collections, col)
+	}
+
+	return c, nil
+}
+
+// GetCollection returns the Collection of the tileset with the given ID
+func GetCollection(id string, base *url.URL) (*Collection, error) {
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
+
+	md, err := ts.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	colURL := resolve(base, "collections", id)
+
+	c := &Collection{
+		ID:          id,
+		Title:       md.Name,
+		Description: md.Description,
+		Attribution: md.Attribution,
+		DataType:    dataType(ts.TileFormat()),
+		Links: []Link{
+			{Href: colURL, Rel: relSelf, Type: contentTypeJSON},
+			{Href: resolve(base, "collections", id, "tiles"), Rel: relTilesets + dataType(ts.TileFormat()), Type: contentTypeJSON},
+		},
+	}
+
+	if md.Bounds != [4]float64{} {
+		c.Extent = &Extent{}
+		c.Extent.Spatial.BBox = [][4]float64{md.Bounds}
+		c.Extent.Spatial.CRS = crsWGS84
+	}
+
+	return c, nil
+}
+
+// TileMatrixSetLimit represents the range of tiles of a zoom level
+type TileMatrixSetLimit struct {
+	TileMatrix string `json:"tileMatrix"`
+	MinTileRow uint64 `json:"minTileRow"`
+	MaxTileRow uint64 `json:"maxTileRow"`
+	MinTileCol uint64 `json:"minTileCol"`
+	MaxTileCol uint64 `json:"maxTileCol"`
+}
+
+// TileSet represents the tiles of a collection in a tile matrix set
+type TileSet struct {
+	Title               string               `json:"title,omitempty"`
+	DataType            string               `json:"dataType"`
+	CRS                 string               `json:"crs"`
+	TileMatrixSetURI    string               `json:"tileMatrixSetURI"`
+	TileMatrixSetLimits []TileMatrixSetLimit `json:"tileMatrixSetLimits,omitempty"`
+	Links               []Link               `json:"links"`
+}
+
+// TileSets represents the list of tilesets of a collection
+type TileSets struct {
+	Links    []Link     `json:"links"`
+	TileSets []*TileSet `json:"tilesets"`
+}
+
+// GetTileSets returns the tilesets of the collection with the given ID
+func GetTileSets(id string, base *url.URL) (*TileSets, error) {
+	ts, err := GetTileSet(id, tms.ID, base)
+	if err != nil {
+		return nil, err
+	}
+
+	// the list only contains a summary of the tilesets
+	ts.TileMatrixSetLimits = nil
+
+	sets := &TileSets{
+		Links: []Link{
+			{Href: resolve(base, "collections", id, "tiles"), Rel: relSelf, Type: contentTypeJSON},
+		},
+		TileSets: []*TileSet{ts},
+	}
+
+	return sets, nil
+}
+
+// GetTileSet returns the TileSet of the collection with the given ID in the
+// given tile matrix set
+func GetTileSet(id, tmsID string, base *url.URL) (*TileSet, error) {
+	if tmsID != tms.ID {
+		return nil, fmt.Errorf("%w: tile matrix set %q", ErrNoEntity, tmsID)
+	}
+
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
+
+	md, err := ts.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	format := ts.TileFormat()
+	tsURL := resolve(base, "collections", id, "tiles", tms.ID)
+
+	set := &TileSet{
+		Title:            md.Name,
+		DataType:         dataType(format),
+		CRS:              tms.CRS,
+		TileMatrixSetURI: tms.URI,
+		Links: []Link{
+			{Href: tsURL, Rel: relSelf, Type: contentTypeJSON},
+			{Href: resolve(base, "tileMatrixSets", tms.ID), Rel: relTilingScheme, Type: contentTypeJSON},
+			{Href: tsURL + "/{tileMatrix}/{tileRow}/{tileCol}", Rel: relItem, Type: format.ContentType(), Templated: true},
+		},
+	}
+
+	for z := md.MinZoom; z <= md.MaxZoom && z <= tms.MaxZoom; z++ {
+		set.TileMatrixSetLimits = append(set.TileMatrixSetLimits, newTileMatrixSetLimit(md.Bounds, uint8(z)))
+	}
+
+	return set, nil
+}
+
+func newTileMatrixSetLimit(bounds [4]float64, z uint8) TileMatrixSetLimit {
+	if bounds == [4]float64{} {
+		bounds = [4]float64{-180, -tms.MaxLatitude, 180, tms.MaxLatitude}
+	}
+
+	minCol, minRow := tms.LonLatToTile(bounds[0], bounds[3], z)
+	maxCol, maxRow := tms.LonLatToTile(bounds[2], bounds[1], z)
+
+	return TileMatrixSetLimit{
+		TileMatrix: fmt.Sprint(z),
+		MinTileRow: minRow,
+		MaxTileRow: maxRow,
+		MinTileCol: minCol,
+		MaxTileCol: maxCol,
+	}
+}
+
+// TileMatrixSetRef represents a reference to a tile matrix set
+type TileMatrixSetRef struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URI   string `json:"uri"`
+	Links []Link `json:"links"`
+}
+
+// TileMatrixSets represents the list of supported tile matrix sets
+type TileMatrixSets struct {
+	TileMatrixSets []TileMatrixSetRef `json:"tileMatrixSets"`
+}
+
+// GetTileMatrixSets returns the list of supported tile matrix sets
+func GetTileMatrixSets(base *url.URL) *TileMatrixSets {
+	return &TileMatrixSets{
+		TileMatrixSets: []TileMatrixSetRef{
+			{
+				ID:    tms.ID,
+				Title: webMercatorQuadTitle,
+				URI:   tms.URI,
+				Links: []Link{
+					{Href: resolve(base, "tileMatrixSets", tms.ID), Rel: relSelf, Type: contentTypeJSON},
+				},
+			},
+		},
+	}
+}
+
+const webMercatorQuadTitle = "Google Maps Compatible for the World"
+
+// TileMatrixSet represents the description of a tile matrix set
+type TileMatrixSet struct {
+	ID                string           `json:"id"`
+	Title             string           `json:"title"`
+	URI               string           `json:"uri"`
+	CRS               string           `json:"crs"`
+	OrderedAxes       []string         `json:"orderedAxes"`
+	WellKnownScaleSet string           `json:"wellKnownScaleSet"`
+	TileMatrices      []tms.TileMatrix `json:"tileMatrices"`
+}
+
+// GetTileMatrixSet returns the tile matrix set with the given ID
+func GetTileMatrixSet(id string) (*TileMatrixSet, error) {
+	if id != tms.ID {
+		return nil, fmt.Errorf("%w: tile matrix set %q", ErrNoEntity, id)
+	}
+
+	set := &TileMatrixSet{
+		ID:                tms.ID,
+		Title:             webMercatorQuadTitle,
+		URI:               tms.URI,
+		CRS:               tms.CRS,
+		OrderedAxes:       []string{"X", "Y"},
+		WellKnownScaleSet: tms.WellKnownScaleSet,
+		TileMatrices:      make([]tms.TileMatrix, 0, tms.MaxZoom+1),
+	}
+
+	for z := uint8(0); z <= tms.MaxZoom; z++ {
+		set.TileMatrices = append(set.TileMatrices, tms.NewTileMatrix(z))
+	}
+
+	return set, nil
+}
+
+// getTileset returns the tileset with the given ID or ErrNoEntity
+func getTileset(id string) (*tileset.Handle, error) {
+	ts, err := tileset.Get(id)
+	if err != nil {
+		if err == mbtiles.ErrTilesetNotFound {
+			return nil, fmt.Errorf("%w: %v", ErrNoEntity, err)
+		}
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+func dataType(f mbtiles.TileFormat) string {
+	if f.IsRaster() {
+		return "map"
+	}
+
+	return "vector"
+}
+
+// resolve appends the path segments to the URL
+func resolve(base *url.URL, segments ...string) string {
+	u := *base
+	for _, s := range segments {
+		u.Path += "/" + s
+	}
+	u.RawPath = ""
+
+	return u.String()
+}
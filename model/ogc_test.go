@@ -0,0 +1,46 @@
+package model
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+)
+
+func TestGetCollections(t *testing.T) {
+	register(t, "ogc-readable", &tilesettest.Provider{
+		Format:   mbtiles.PNG,
+		Metadata: mbtiles.Metadata{Name: "Readable"},
+	})
+	register(t, "ogc-unreadable", &tilesettest.Provider{
+		Format:        mbtiles.PNG,
+		MetadataError: errors.New("unreadable"),
+	})
+
+	c, err := GetCollections(&url.URL{Scheme: "http", Host: "localhost", Path: "/ogc/"})
+	if err != nil {
+		t.Fatalf("GetCollections() error = %v", err)
+	}
+
+	var ids []string
+	for _, col := range c.Collections {
+		if strings.HasPrefix(col.ID, "ogc-") {
+			ids = append(ids, col.ID)
+		}
+	}
+	if len(ids) != 1 || ids[0] != "ogc-readable" {
+		t.Errorf("collections = %v, want only the readable tileset", ids)
+	}
+}
+
+func TestGetConformance(t *testing.T) {
+	for _, class := range GetConformance().ConformsTo {
+		// the tilesets of the dataset are not served
+		if strings.HasSuffix(class, "/tilesets-list") || strings.HasSuffix(class, "/geodata-tilesets") {
+			t.Errorf("conformance class %q of a missing resource declared", class)
+		}
+	}
+}
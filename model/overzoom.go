@@ -0,0 +1,73 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tileset"
+)
+
+// maxOverzoom is the number of zoom levels beyond the maximum zoom level of
+// vector tilesets that are generated, overzooming is disabled if zero
+var maxOverzoom uint8
+
+// SetMaxOverzoom sets the number of zoom levels vector tiles are overzoomed
+func SetMaxOverzoom(n int) {
+	maxOverzoom = uint8(n)
+}
+
+// overzoom generates the vector tile beyond the maximum zoom level of the
+// tileset from its ancestor at the maximum zoom level. The geometries of the
+// ancestor are scaled and clipped to the tile, which is returned in the
+// encoding of the tileset.
+func overzoom(ts *tileset.Handle, tc *mbtiles.TileCoord) ([]byte, error) {
+	md, err := ts.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if md.MaxZoom < 0 || int(tc.Z) <= md.MaxZoom || int(tc.Z)-md.MaxZoom > int(maxOverzoom) {
+		return nil, mbtiles.ErrTileNotFound
+	}
+
+	dz := tc.Z - uint8(md.MaxZoom)
+
+	// the relative position of the tile is counted from the top left
+	y := (uint64(1) << tc.Z) - 1 - tc.Y
+	parent := &mbtiles.TileCoord{
+		Z: uint8(md.MaxZoom),
+		X: tc.X >> dz,
+		Y: (uint64(1) << md.MaxZoom) - 1 - (y >> dz),
+	}
+
+	data, err := ts.GetTile(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := ts.TileCompression()
+
+	raw, err := compression.Decode(data, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	vt, err := mvt.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode vector tile: %w", err)
+	}
+
+	mask := uint64(1)<<dz - 1
+	vt = mvt.Overzoom(vt, dz, uint32(tc.X&mask), uint32(y&mask))
+	if len(vt.Layers) == 0 {
+		return nil, mbtiles.ErrTileNotFound
+	}
+
+	if raw, err = mvt.Encode(vt); err != nil {
+		return nil, err
+	}
+
+	return compression.Encode(raw, enc)
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+)
+
+// pointTile returns a gzip compressed vector tile with a point in the center
+// and the name of the tile as property
+func pointTile(t *testing.T, name string) []byte {
+	f := &mvt.Feature{Type: mvt.Point, Tags: []uint32{0, 0}}
+	f.EncodeGeometry([][]mvt.Coord{{{X: 2048, Y: 2048}}})
+
+	return tilesettest.VectorTile(t, &mvt.Tile{Layers: []*mvt.Layer{{
+		Version:  2,
+		Name:     "points",
+		Extent:   4096,
+		Keys:     []string{"tile"},
+		Values:   []mvt.Value{mvt.NewValue(name)},
+		Features: []*mvt.Feature{f},
+	}}}, compression.Gzip)
+}
+
+func TestOverzoom(t *testing.T) {
+	// the tiles of zoom level 1 are keyed by their TMS row
+	register(t, "overzoom", &tilesettest.Provider{
+		Format:      mbtiles.PBF,
+		Compression: compression.Gzip,
+		Metadata:    mbtiles.Metadata{MinZoom: 0, MaxZoom: 1},
+		Tiles: map[mbtiles.TileCoord][]byte{
+			{Z: 1, X: 0, Y: 1}: pointTile(t, "top left"),
+			{Z: 1, X: 1, Y: 1}: pointTile(t, "top right"),
+			{Z: 1, X: 0, Y: 0}: pointTile(t, "bottom left"),
+		},
+	})
+
+	SetMaxOverzoom(2)
+	t.Cleanup(func() { SetMaxOverzoom(0) })
+
+	tests := []struct {
+		name    string
+		z, x, y string
+		parent  string
+		point   mvt.Coord
+		err     error
+	}{
+		{"maximum zoom", "1", "1", "0", "top right", mvt.Coord{X: 2048, Y: 2048}, nil},
+		{"child of the top left", "2", "1", "1", "top left", mvt.Coord{X: 0, Y: 0}, nil},
+		{"child of the top right", "2", "2", "1", "top right", mvt.Coord{X: 4096, Y: 0}, nil},
+		{"child of the bottom left", "2", "0", "2", "bottom left", mvt.Coord{X: 4096, Y: 4096}, nil},
+		{"two levels", "3", "6", "2", "top right", mvt.Coord{X: 0, Y: 0}, nil},
+		{"beyond max overzoom", "4", "8", "8", "", mvt.Coord{}, mbtiles.ErrTileNotFound},
+		{"missing parent", "2", "3", "3", "", mvt.Coord{}, mbtiles.ErrTileNotFound},
+		{"point clipped away", "3", "0", "0", "", mvt.Coord{}, mbtiles.ErrTileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tile, err := GetTile("overzoom", tt.z, tt.x, tt.y)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GetTile() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			raw, err := compression.Decode(tile.Data, tile.Encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vt, err := mvt.Decode(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := vt.Layer("points")
+			if l == nil || len(l.Features) != 1 {
+				t.Fatalf("tile = %+v, want one point", vt)
+			}
+
+			f := l.Features[0]
+			if got := l.Properties(f)["tile"]; got != tt.parent {
+				t.Errorf("parent = %v, want %v", got, tt.parent)
+			}
+
+			parts, err := f.DecodeGeometry()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parts) != 1 || len(parts[0]) != 1 || parts[0][0] != tt.point {
+				t.Errorf("point = %v, want %v", parts, tt.point)
+			}
+		})
+	}
+}
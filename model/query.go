@@ -0,0 +1,160 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+// The search radius of a point query in pixels. The default covers the
+// inaccuracy of a click or tap on a point or line.
+const (
+	defaultQueryRadius = 5
+	maxQueryRadius     = tms.TileSize
+)
+
+// PointQuery defines a search for the features of a vector tileset around
+// a WGS84 position
+type PointQuery struct {
+	Lng, Lat float64
+	Z        uint8
+
+	// Radius is the search radius in pixels at the zoom level
+	Radius float64
+
+	// Layers are the sorted names of the searched layers, all if empty
+	Layers []string
+}
+
+// ParsePointQuery parses the PointQuery from the query parameters lng, lat,
+// z, radius and layers. The radius is optional.
+func ParsePointQuery(q url.Values) (*PointQuery, error) {
+	pq := &PointQuery{Radius: defaultQueryRadius}
+
+	float := func(name string, lo, hi float64) (float64, error) {
+		v, err := strconv.ParseFloat(q.Get(name), 64)
+		if err != nil || math.IsNaN(v) || v < lo || v > hi {
+			return 0, fmt.Errorf("%w: %s must be a number between %v and %v", ErrBadInput, name, lo, hi)
+		}
+		return v, nil
+	}
+
+	var err error
+	if pq.Lng, err = float("lng", -180, 180); err != nil {
+		return nil, err
+	}
+	if pq.Lat, err = float("lat", -tms.MaxLatitude, tms.MaxLatitude); err != nil {
+		return nil, err
+	}
+
+	z, err := strconv.ParseUint(q.Get("z"), 10, 8)
+	if err != nil || z > tms.MaxZoom {
+		return nil, fmt.Errorf("%w: z must be an integer between 0 and %v", ErrBadInput, tms.MaxZoom)
+	}
+	pq.Z = uint8(z)
+
+	if v := q.Get("radius"); len(v) > 0 {
+		if pq.Radius, err = float("radius", 0, maxQueryRadius); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := q.Get(layersParam); len(v) > 0 {
+		pq.Layers = ParseLayers(v)
+	}
+
+	return pq, nil
+}
+
+// QueryFeatures returns the features of the vector tile covering the position
+// of the query that are within the radius, ordered by their distance. The
+// layer of a feature is its property layer.
+func QueryFeatures(id string, pq *PointQuery) (*GeoJSONFeatureCollection, error) {
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	format := ts.TileFormat()
+	ts.Release()
+
+	if format != mbtiles.PBF {
+		return nil, fmt.Errorf("%w: features can only be queried in vector tilesets", ErrBadInput)
+	}
+
+	px, py := tms.LonLatToPixel(pq.Lng, pq.Lat, pq.Z)
+	x, y := tms.LonLatToTile(pq.Lng, pq.Lat, pq.Z)
+
+	fc := newFeatureCollection()
+
+	z := strconv.Itoa(int(pq.Z))
+	tile, err := GetTile(id, z, strconv.FormatUint(x, 10), strconv.FormatUint(y, 10))
+	if errors.Is(err, mbtiles.ErrTileNotFound) {
+		return fc, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	raw, err := compression.Decode(tile.Data, tile.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	vt, err := mvt.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode vector tile: %w", err)
+	}
+
+	type match struct {
+		feature  *GeoJSONFeature
+		distance float64
+	}
+	var matches []match
+
+	for _, l := range vt.Layers {
+		if len(pq.Layers) > 0 {
+			if i := sort.SearchStrings(pq.Layers, l.Name); i == len(pq.Layers) || pq.Layers[i] != l.Name {
+				continue
+			}
+		}
+
+		// the position and radius in the coordinate space of the layer
+		extent := layerExtent(l)
+		scale := float64(extent) / tms.TileSize
+		p := mvt.Coord{
+			X: (px - float64(x*tms.TileSize)) * scale,
+			Y: (py - float64(y*tms.TileSize)) * scale,
+		}
+		radius := pq.Radius * scale
+
+		project := lonLatProjection(pq.Z, x, y, extent)
+
+		for _, f := range l.Features {
+			d, err := f.Distance(p)
+			if err != nil || d > radius {
+				continue
+			}
+
+			if feature := newGeoJSONFeature(l, f, project); feature != nil {
+				matches = append(matches, match{feature, d})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	for _, m := range matches {
+		fc.Features = append(fc.Features, m.feature)
+	}
+
+	return fc, nil
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/compression"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/mvt"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+func TestParsePointQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  *PointQuery
+	}{
+		{"default radius", "lng=10.5&lat=-20&z=3", &PointQuery{Lng: 10.5, Lat: -20, Z: 3, Radius: defaultQueryRadius}},
+		{"radius and layers", "lng=180&lat=0&z=0&radius=0&layers=loot,labels", &PointQuery{Lng: 180, Z: 0, Layers: []string{"labels", "loot"}}},
+		{"maximum radius", "lng=-180&lat=85&z=24&radius=256", &PointQuery{Lng: -180, Lat: 85, Z: 24, Radius: 256}},
+		{"missing lng", "lat=0&z=0", nil},
+		{"lng out of range", "lng=180.1&lat=0&z=0", nil},
+		{"lat out of range", "lng=0&lat=86&z=0", nil},
+		{"lat not a number", "lng=0&lat=NaN&z=0", nil},
+		{"missing z", "lng=0&lat=0", nil},
+		{"negative z", "lng=0&lat=0&z=-1", nil},
+		{"z out of range", "lng=0&lat=0&z=25", nil},
+		{"negative radius", "lng=0&lat=0&z=0&radius=-1", nil},
+		{"radius out of range", "lng=0&lat=0&z=0&radius=257", nil},
+		{"invalid radius", "lng=0&lat=0&z=0&radius=a", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParsePointQuery(q)
+			if tt.want == nil {
+				if !errors.Is(err, ErrBadInput) {
+					t.Errorf("ParsePointQuery() error = %v, want %v", err, ErrBadInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePointQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePointQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// namedPoints returns a gzip compressed vector tile with a layer of points
+// with the names as property
+func namedPoints(t *testing.T, layer string, points map[string]mvt.Coord) []byte {
+	l := &mvt.Layer{Version: 2, Name: layer, Extent: mvt.DefaultExtent, Keys: []string{"name"}}
+	for name, c := range points {
+		f := &mvt.Feature{Type: mvt.Point, Tags: []uint32{0, uint32(len(l.Values))}}
+		f.EncodeGeometry([][]mvt.Coord{{c}})
+
+		l.Values = append(l.Values, mvt.NewValue(name))
+		l.Features = append(l.Features, f)
+	}
+
+	return tilesettest.VectorTile(t, &mvt.Tile{Layers: []*mvt.Layer{l}}, compression.Gzip)
+}
+
+func TestQueryFeatures(t *testing.T) {
+	// the two tiles of the northern hemisphere at zoom level 1 by their TMS
+	// row, a pixel has 16 units of the extent
+	register(t, "query", &tilesettest.Provider{
+		Format:      mbtiles.PBF,
+		Compression: compression.Gzip,
+		Metadata:    mbtiles.Metadata{MinZoom: 1, MaxZoom: 1},
+		Tiles: map[mbtiles.TileCoord][]byte{
+			{Z: 1, X: 0, Y: 1}: namedPoints(t, "points", map[string]mvt.Coord{
+				"west":     {X: 4095, Y: 2048},
+				"west-far": {X: 4055, Y: 2048},
+			}),
+			{Z: 1, X: 1, Y: 1}: namedPoints(t, "points", map[string]mvt.Coord{
+				"east":         {X: 1, Y: 2048},
+				"antimeridian": {X: 4095, Y: 2048},
+			}),
+		},
+	})
+	register(t, "query-raster", &tilesettest.Provider{Format: mbtiles.PNG})
+
+	// the latitude of the middle of the northern tiles
+	_, lat := tms.PixelToLonLat(0, 128, 1)
+	lng := func(px float64) float64 {
+		lng, _ := tms.PixelToLonLat(px, 128, 1)
+		return lng
+	}
+
+	tests := []struct {
+		name string
+		pq   PointQuery
+		want []string
+	}{
+		{"west of the edge", PointQuery{Lng: lng(255.5), Lat: lat, Z: 1, Radius: defaultQueryRadius}, []string{"west", "west-far"}},
+		{"east of the edge", PointQuery{Lng: lng(256.5), Lat: lat, Z: 1, Radius: defaultQueryRadius}, []string{"east"}},
+		{"on the edge", PointQuery{Lng: 0, Lat: lat, Z: 1, Radius: defaultQueryRadius}, []string{"east"}},
+		{"antimeridian", PointQuery{Lng: 180, Lat: lat, Z: 1, Radius: defaultQueryRadius}, []string{"antimeridian"}},
+		{"small radius", PointQuery{Lng: lng(255.5), Lat: lat, Z: 1, Radius: 1}, []string{"west"}},
+		{"zero radius", PointQuery{Lng: lng(255.5), Lat: lat, Z: 1}, nil},
+		{"other layer", PointQuery{Lng: lng(255.5), Lat: lat, Z: 1, Radius: defaultQueryRadius, Layers: []string{"lines"}}, nil},
+		{"missing tile", PointQuery{Lng: 0, Lat: -lat, Z: 1, Radius: defaultQueryRadius}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pq := tt.pq
+			fc, err := QueryFeatures("query", &pq)
+			if err != nil {
+				t.Fatalf("QueryFeatures() error = %v", err)
+			}
+
+			var got []string
+			for _, f := range fc.Features {
+				got = append(got, f.Properties["name"].(string))
+
+				// the positions are within the radius of about 3.5 degrees
+				pos := f.Geometry.Coordinates.([2]float64)
+				if math.Abs(pos[1]-lat) > 0.1 || math.Abs(pos[0]-pq.Lng) > 3.5 {
+					t.Errorf("position of %v = %v, want near %v, %v", f.Properties["name"], pos, pq.Lng, lat)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("features = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := QueryFeatures("query-raster", &PointQuery{}); !errors.Is(err, ErrBadInput) {
+		t.Errorf("QueryFeatures() of a raster tileset error = %v, want %v", err, ErrBadInput)
+	}
+}
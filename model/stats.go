@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/core/tms"
+)
+
+// TilesetStats describes the tiles of a tileset and their coverage by zoom
+// level as GeoJSON footprint
+type TilesetStats struct {
+	*mbtiles.Stats
+
+	Modified time.Time                 `json:"modified"`
+	Coverage *GeoJSONFeatureCollection `json:"coverage"`
+}
+
+// statsEntry holds the statistics of a tileset until it is modified. The
+// done channel is closed once they are computed.
+type statsEntry struct {
+	modified time.Time
+	done     chan struct{}
+	stats    *TilesetStats
+	err      error
+}
+
+var (
+	statsCache = map[string]*statsEntry{}
+	statsMu    sync.Mutex
+)
+
+func init() {
+	tileset.OnChange(func(id string) {
+		statsMu.Lock()
+		delete(statsCache, id)
+		statsMu.Unlock()
+	})
+}
+
+// GetTilesetStats returns the statistics of the tileset by given ID. They are
+// computed once for every modification time of the tileset.
+func GetTilesetStats(id string) (*TilesetStats, error) {
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
+
+	a, ok := ts.Provider.(tileset.Analyzer)
+	if !ok {
+		return nil, fmt.Errorf("%w: tileset %q does not support statistics", ErrBadInput, id)
+	}
+
+	modified := ts.ModTime()
+
+	statsMu.Lock()
+	e, ok := statsCache[id]
+	if ok && e.modified.Equal(modified) {
+		statsMu.Unlock()
+		<-e.done
+		return e.stats, e.err
+	}
+
+	e = &statsEntry{modified: modified, done: make(chan struct{})}
+	statsCache[id] = e
+	statsMu.Unlock()
+
+	var s *mbtiles.Stats
+	if s, e.err = a.Stats(); e.err == nil {
+		e.stats = &TilesetStats{
+			Stats:    s,
+			Modified: modified,
+			Coverage: newCoverageCollection(s.Zooms),
+		}
+	}
+	close(e.done)
+
+	// failures are not cached
+	if e.err != nil {
+		statsMu.Lock()
+		if statsCache[id] == e {
+			delete(statsCache, id)
+		}
+		statsMu.Unlock()
+	}
+
+	return e.stats, e.err
+}
+
+// newCoverageCollection returns a feature for every zoom level with the
+// covered cells as multi polygon
+func newCoverageCollection(zooms []*mbtiles.ZoomStats) *GeoJSONFeatureCollection {
+	fc := newFeatureCollection()
+
+	for _, zs := range zooms {
+		c := zs.Coverage
+
+		var polygons [][][][2]float64
+		c.Runs(func(x, y, n uint64) {
+			west, north := tms.PixelToLonLat(float64(x*tms.TileSize), float64(y*tms.TileSize), c.Zoom)
+			east, south := tms.PixelToLonLat(float64((x+n)*tms.TileSize), float64((y+1)*tms.TileSize), c.Zoom)
+			west, north, east, south = roundCoord(west), roundCoord(north), roundCoord(east), roundCoord(south)
+
+			ring := [][2]float64{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}
+			polygons = append(polygons, [][][2]float64{ring})
+		})
+
+		if len(polygons) == 0 {
+			continue
+		}
+
+		fc.Features = append(fc.Features, &GeoJSONFeature{
+			Type:     geoJSONFeature,
+			Geometry: &GeoJSONGeometry{Type: "MultiPolygon", Coordinates: polygons},
+			Properties: map[string]interface{}{
+				"zoom":      zs.Zoom,
+				"cell_zoom": c.Zoom,
+			},
+		})
+	}
+
+	return fc
+}
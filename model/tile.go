@@ -4,9 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"time"
 
+	"github.com/tarkov-database/tileserver/core/cache"
+	"github.com/tarkov-database/tileserver/core/compression"
 	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
 
 	"github.com/zeebo/blake3"
 )
@@ -17,51 +21,69 @@ var (
 )
 
 const (
-	tileJSONVersion = "2.2.0"
-	tileJSONScheme  = "xyz"
+	tileJSONVersion2 = "2.2.0"
+	tileJSONVersion3 = "3.0.0"
+	tileJSONScheme   = "xyz"
+
+	// tileJSONParam is the query parameter to request a TileJSON version
+	tileJSONParam = "tilejson"
 )
 
 // TileJSON describes a tileset in JSON format
 type TileJSON struct {
-	// TileJSON spec fields
-	TileJSON    string     `json:"tilejson"`
-	Name        string     `json:"name,omitempty"`
-	Description string     `json:"description,omitempty"`
-	Version     string     `json:"version,omitempty"`
-	Attribution string     `json:"attribution,omitempty"`
-	Template    string     `json:"template,omitempty"`
-	Legend      string     `json:"legend,omitempty"`
-	Scheme      string     `json:"scheme,omitempty"`
-	Tiles       []string   `json:"tiles"`
-	Grids       []string   `json:"grids,omitempty"`
-	Data        []string   `json:"data,omitempty"`
-	MinZoom     int        `json:"minzoom,omitempty"`
-	MaxZoom     int        `json:"maxzoom,omitempty"`
-	Bounds      [4]float64 `json:"bounds,omitempty"`
-	Center      [3]float64 `json:"center,omitempty"`
-
-	// Custom fields
-	Format string `json:"format,omitempty"`
-	Type   string `json:"type,omitempty"`
-
-	*mbtiles.LayerData `json:",omitempty"`
+	TileJSON     string         `json:"tilejson"`
+	Tiles        []string       `json:"tiles"`
+	VectorLayers *[]VectorLayer `json:"vector_layers,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Description  string         `json:"description,omitempty"`
+	Version      string         `json:"version,omitempty"`
+	Attribution  string         `json:"attribution,omitempty"`
+	Template     string         `json:"template,omitempty"`
+	Legend       string         `json:"legend,omitempty"`
+	Scheme       string         `json:"scheme,omitempty"`
+	Grids        []string       `json:"grids,omitempty"`
+	Data         []string       `json:"data,omitempty"`
+	MinZoom      int            `json:"minzoom"`
+	MaxZoom      int            `json:"maxzoom"`
+	FillZoom     *int           `json:"fillzoom,omitempty"`
+	Bounds       *[4]float64    `json:"bounds,omitempty"`
+	Center       *[3]float64    `json:"center,omitempty"`
+}
+
+// VectorLayer describes a layer of a vector tileset
+type VectorLayer struct {
+	ID          string            `json:"id"`
+	Fields      map[string]string `json:"fields"`
+	Description string            `json:"description,omitempty"`
+	MinZoom     *int              `json:"minzoom,omitempty"`
+	MaxZoom     *int              `json:"maxzoom,omitempty"`
 }
 
-// GetTileJSON returns a TileJSON by given tileset ID
+// GetTileJSON returns a TileJSON by given tileset ID. The TileJSON version can
+// be requested by the tilejson query parameter, version 3.0.0 is the default.
 func GetTileJSON(id string, u *url.URL) (*TileJSON, error) {
-	ts, err := mbtiles.GetTileset(id)
-	if err != nil {
-		switch err {
-		case mbtiles.ErrTilesetNotFound:
-			return nil, fmt.Errorf("%w: %v", ErrNoEntity, err)
+	q := u.Query()
+
+	version := tileJSONVersion3
+	if v := q.Get(tileJSONParam); len(v) > 0 {
+		switch v {
+		case tileJSONVersion2, tileJSONVersion3:
+			version = v
 		default:
-			return nil, err
+			return nil, fmt.Errorf("%w: unsupported TileJSON version %q", ErrBadInput, v)
 		}
 	}
+	q.Del(tileJSONParam)
+
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
 
 	tsURL := fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, u.EscapedPath())
 	query := ""
-	if q := u.Query().Encode(); len(q) > 0 {
+	if q := q.Encode(); len(q) > 0 {
 		query = "?" + q
 	}
 
@@ -71,74 +93,239 @@ func GetTileJSON(id string, u *url.URL) (*TileJSON, error) {
 	}
 
 	tj := &TileJSON{
-		TileJSON:    tileJSONVersion,
+		TileJSON:    version,
 		Name:        md.Name,
 		Description: md.Description,
 		Version:     md.Version,
 		Attribution: md.Attribution,
 		Scheme:      tileJSONScheme,
-		Format:      md.Format.String(),
-		Type:        md.Type.String(),
 		Tiles: []string{
-			fmt.Sprintf("%s/tiles/{z}/{x}/{y}.%s%s", tsURL, ts.Format, query),
+			fmt.Sprintf("%s/tiles/{z}/{x}/{y}.%s%s", tsURL, ts.TileFormat(), query),
 		},
-		MinZoom:   md.MinZoom,
-		MaxZoom:   md.MaxZoom,
-		Bounds:    md.Bounds,
-		Center:    md.Center,
-		LayerData: md.LayerData,
+		MinZoom: md.MinZoom,
+		MaxZoom: md.MaxZoom,
 	}
 
-	if ts.UTFGrid {
+	if md.Bounds != [4]float64{} {
+		tj.Bounds = &md.Bounds
+	}
+
+	if md.Center != [3]float64{} {
+		tj.Center = &md.Center
+	}
+
+	if ts.TileFormat() == mbtiles.PBF {
+		vl := newVectorLayers(md.LayerData)
+		if v := q.Get(layersParam); len(v) > 0 {
+			vl = filterVectorLayers(vl, ParseLayers(v))
+		}
+		tj.VectorLayers = &vl
+
+		if version == tileJSONVersion3 {
+			tj.FillZoom = md.FillZoom
+		}
+	}
+
+	if ts.GridFormat() != mbtiles.UNKNOWN {
 		tj.Grids = []string{fmt.Sprintf("%s/tiles/{z}/{x}/{y}.json%s", tsURL, query)}
 	}
 
 	return tj, nil
 }
 
+// filterVectorLayers returns the vector layers with the given sorted names
+func filterVectorLayers(vl []VectorLayer, names []string) []VectorLayer {
+	filtered := make([]VectorLayer, 0, len(names))
+	for _, l := range vl {
+		if i := sort.SearchStrings(names, l.ID); i < len(names) && names[i] == l.ID {
+			filtered = append(filtered, l)
+		}
+	}
+
+	return filtered
+}
+
+// newVectorLayers converts the vector layers of the MBTiles metadata into
+// spec conform layers, vector tilesets require the field even without layers
+func newVectorLayers(ld *mbtiles.LayerData) []VectorLayer {
+	layers := make([]VectorLayer, 0)
+	if ld == nil || ld.VectorLayers == nil {
+		return layers
+	}
+
+	for _, l := range *ld.VectorLayers {
+		vl := VectorLayer{
+			ID:          l.ID,
+			Fields:      make(map[string]string, len(l.Fields)),
+			Description: l.Description,
+		}
+
+		for k, v := range l.Fields {
+			if s, ok := v.(string); ok {
+				vl.Fields[k] = s
+			} else {
+				vl.Fields[k] = fmt.Sprint(v)
+			}
+		}
+
+		if l.MinZoom > 0 {
+			minZoom := l.MinZoom
+			vl.MinZoom = &minZoom
+		}
+		if l.MaxZoom > 0 {
+			maxZoom := l.MaxZoom
+			vl.MaxZoom = &maxZoom
+		}
+
+		layers = append(layers, vl)
+	}
+
+	return layers
+}
+
 type Tile struct {
 	Data     []byte
 	Format   mbtiles.TileFormat
+	Encoding compression.Encoding
 	Modified time.Time
 	Hash     [32]byte
+
+	// Cached is true if the tile was served from the tile cache
+	Cached bool
+
+	// Callback is the JSONP callback the grid is wrapped in
+	Callback string
+}
+
+// TileEncodings are the content encodings vector tiles can be served in
+var TileEncodings = []compression.Encoding{
+	compression.Identity,
+	compression.Gzip,
+	compression.Deflate,
+	compression.Brotli,
+	compression.Zstd,
+}
+
+// variantCacheSize is the number of transcoded tiles kept in memory
+const variantCacheSize = 4096
+
+var variants = compression.NewCache(variantCacheSize)
+
+// EncodeTile returns the tile transcoded into the given encoding
+func EncodeTile(t *Tile, e compression.Encoding) (*Tile, error) {
+	if t.Encoding == e {
+		return t, nil
+	}
+
+	key := compression.VariantKey{Hash: t.Hash, Encoding: e}
+
+	data, ok := variants.Get(key)
+	if !ok {
+		var err error
+		if data, err = compression.Transcode(t.Data, t.Encoding, e); err != nil {
+			return nil, err
+		}
+		variants.Add(key, data)
+	}
+
+	tile := *t
+	tile.Data = data
+	tile.Encoding = e
+
+	return &tile, nil
 }
 
 func GetTile(id, z, x, y string) (*Tile, error) {
-	ts, err := mbtiles.GetTileset(id)
+	ts, err := tileset.Get(id)
 	if err != nil {
 		return nil, err
 	}
+	defer ts.Release()
 
 	tc, err := mbtiles.ParseTileCoord(z, x, y)
 	if err != nil {
 		return nil, err
 	}
 
+	key := cache.Key{Tileset: id, Generation: ts.Generation(), Z: tc.Z, X: tc.X, Y: tc.Y}
+
+	tile := &Tile{
+		Format:   ts.TileFormat(),
+		Encoding: ts.TileCompression(),
+		Modified: ts.ModTime(),
+	}
+
+	if e, ok := tileCache.Get(key); ok {
+		tile.Data, tile.Hash, tile.Cached = e.Data, e.Hash, true
+		return tile, nil
+	}
+
+	// an indexed tile exists, so it is never overzoomed
+	var indexed bool
+	if idx, ok := ts.Provider.(tileset.Indexer); ok {
+		tile.Hash, indexed = idx.TileHash(tc)
+	}
+
 	data, err := ts.GetTile(tc)
+	if errors.Is(err, mbtiles.ErrTileNotFound) && tile.Format == mbtiles.PBF && maxOverzoom > 0 {
+		data, err = overzoom(ts, tc)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	h := blake3.New()
-	h.Write(data)
+	tile.Data = data
+
+	if !indexed {
+		tile.Hash = blake3.Sum256(data)
+	}
+
+	tileCache.Add(key, &cache.Entry{Data: tile.Data, Hash: tile.Hash})
+
+	return tile, nil
+}
+
+// GetIndexedTile returns the tile without its data if its hash is stored in
+// the ETag index of the tileset. It allows to answer conditional requests
+// without reading the tile, the returned bool is false if the tile is not
+// indexed.
+func GetIndexedTile(id, z, x, y string) (*Tile, bool) {
+	ts, err := tileset.Get(id)
+	if err != nil {
+		return nil, false
+	}
+	defer ts.Release()
+
+	idx, ok := ts.Provider.(tileset.Indexer)
+	if !ok {
+		return nil, false
+	}
 
-	sum := h.Sum(nil)
+	tc, err := mbtiles.ParseTileCoord(z, x, y)
+	if err != nil {
+		return nil, false
+	}
 
 	tile := &Tile{
-		Data:     data,
-		Format:   ts.Format,
-		Modified: ts.Timestamp,
-		Hash:     [32]byte(sum),
+		Format:   ts.TileFormat(),
+		Encoding: ts.TileCompression(),
+		Modified: ts.ModTime(),
 	}
 
-	return tile, nil
+	if tile.Hash, ok = idx.TileHash(tc); !ok {
+		return nil, false
+	}
+
+	return tile, true
 }
 
+// GetGrid returns the UTF grid in the encoding it is stored in
 func GetGrid(id, z, x, y string) (*Tile, error) {
-	ts, err := mbtiles.GetTileset(id)
+	ts, err := tileset.Get(id)
 	if err != nil {
 		return nil, err
 	}
+	defer ts.Release()
 
 	tc, err := mbtiles.ParseTileCoord(z, x, y)
 	if err != nil {
@@ -151,8 +338,11 @@ func GetGrid(id, z, x, y string) (*Tile, error) {
 	}
 
 	tile := &Tile{
-		Data:   data,
-		Format: ts.UTFGridCompression,
+		Data:     data,
+		Format:   mbtiles.JSON,
+		Encoding: ts.GridFormat().Encoding(),
+		Modified: ts.ModTime(),
+		Hash:     blake3.Sum256(data),
 	}
 
 	return tile, nil
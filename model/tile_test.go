@@ -0,0 +1,146 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/core/tileset/tilesettest"
+)
+
+// register adds the provider to the registry for the duration of the test
+func register(t *testing.T, id string, p tileset.Provider) {
+	tileset.Register(id, p)
+	t.Cleanup(func() { tileset.Unregister(id) })
+}
+
+func TestGetTileJSON(t *testing.T) {
+	layers := []mbtiles.VectorLayer{
+		{ID: "labels", Fields: map[string]interface{}{"name": "String"}},
+		{ID: "loot", Fields: map[string]interface{}{"kind": "String", "count": "Number"}, MinZoom: 2, MaxZoom: 4},
+	}
+
+	register(t, "tilejson-vector", &tilesettest.Provider{
+		Format: mbtiles.PBF,
+		Metadata: mbtiles.Metadata{
+			Name:        "Customs",
+			Description: "Map of Customs",
+			Attribution: "Tarkov Database",
+			MinZoom:     0,
+			MaxZoom:     5,
+			Bounds:      [4]float64{-180, -85.0511, 180, 85.0511},
+			Center:      [3]float64{0, 0, 2},
+			LayerData:   &mbtiles.LayerData{VectorLayers: &layers},
+		},
+	})
+	fillZoom := 3
+	register(t, "tilejson-fillzoom", &tilesettest.Provider{
+		Format:   mbtiles.PBF,
+		Metadata: mbtiles.Metadata{Name: "Lighthouse", MinZoom: 0, MaxZoom: 2, FillZoom: &fillZoom},
+	})
+	register(t, "tilejson-raster", &tilesettest.Provider{
+		Format:          mbtiles.PNG,
+		GridCompression: mbtiles.ZLIB,
+		Metadata:        mbtiles.Metadata{Name: "Shoreline", MinZoom: 1, MaxZoom: 3},
+	})
+
+	tests := []struct {
+		name string
+		id   string
+		url  string
+		want string
+		err  error
+	}{
+		{
+			name: "vector 3.0.0",
+			id:   "tilejson-vector",
+			url:  "https://example.com/v1/tilejson-vector",
+			want: `{"tilejson":"3.0.0","tiles":["https://example.com/v1/tilejson-vector/tiles/{z}/{x}/{y}.pbf"],` +
+				`"vector_layers":[{"id":"labels","fields":{"name":"String"}},` +
+				`{"id":"loot","fields":{"count":"Number","kind":"String"},"minzoom":2,"maxzoom":4}],` +
+				`"name":"Customs","description":"Map of Customs","attribution":"Tarkov Database","scheme":"xyz",` +
+				`"minzoom":0,"maxzoom":5,"bounds":[-180,-85.0511,180,85.0511],"center":[0,0,2]}`,
+		},
+		{
+			name: "vector 2.2.0",
+			id:   "tilejson-vector",
+			url:  "https://example.com/v1/tilejson-vector?tilejson=2.2.0",
+			want: `{"tilejson":"2.2.0","tiles":["https://example.com/v1/tilejson-vector/tiles/{z}/{x}/{y}.pbf"],` +
+				`"vector_layers":[{"id":"labels","fields":{"name":"String"}},` +
+				`{"id":"loot","fields":{"count":"Number","kind":"String"},"minzoom":2,"maxzoom":4}],` +
+				`"name":"Customs","description":"Map of Customs","attribution":"Tarkov Database","scheme":"xyz",` +
+				`"minzoom":0,"maxzoom":5,"bounds":[-180,-85.0511,180,85.0511],"center":[0,0,2]}`,
+		},
+		{
+			name: "layers filter",
+			id:   "tilejson-vector",
+			url:  "https://example.com/v1/tilejson-vector?layers=loot&tilejson=3.0.0",
+			want: `{"tilejson":"3.0.0","tiles":["https://example.com/v1/tilejson-vector/tiles/{z}/{x}/{y}.pbf?layers=loot"],` +
+				`"vector_layers":[{"id":"loot","fields":{"count":"Number","kind":"String"},"minzoom":2,"maxzoom":4}],` +
+				`"name":"Customs","description":"Map of Customs","attribution":"Tarkov Database","scheme":"xyz",` +
+				`"minzoom":0,"maxzoom":5,"bounds":[-180,-85.0511,180,85.0511],"center":[0,0,2]}`,
+		},
+		{
+			name: "fillzoom 3.0.0",
+			id:   "tilejson-fillzoom",
+			url:  "https://example.com/v1/tilejson-fillzoom",
+			want: `{"tilejson":"3.0.0","tiles":["https://example.com/v1/tilejson-fillzoom/tiles/{z}/{x}/{y}.pbf"],` +
+				`"vector_layers":[],"name":"Lighthouse","scheme":"xyz","minzoom":0,"maxzoom":2,"fillzoom":3}`,
+		},
+		{
+			name: "fillzoom 2.2.0",
+			id:   "tilejson-fillzoom",
+			url:  "https://example.com/v1/tilejson-fillzoom?tilejson=2.2.0",
+			want: `{"tilejson":"2.2.0","tiles":["https://example.com/v1/tilejson-fillzoom/tiles/{z}/{x}/{y}.pbf"],` +
+				`"vector_layers":[],"name":"Lighthouse","scheme":"xyz","minzoom":0,"maxzoom":2}`,
+		},
+		{
+			name: "raster with grids",
+			id:   "tilejson-raster",
+			url:  "http://localhost:8080/v1/tilejson-raster",
+			want: `{"tilejson":"3.0.0","tiles":["http://localhost:8080/v1/tilejson-raster/tiles/{z}/{x}/{y}.png"],` +
+				`"name":"Shoreline","scheme":"xyz","grids":["http://localhost:8080/v1/tilejson-raster/tiles/{z}/{x}/{y}.json"],` +
+				`"minzoom":1,"maxzoom":3}`,
+		},
+		{
+			name: "unsupported version",
+			id:   "tilejson-vector",
+			url:  "https://example.com/v1/tilejson-vector?tilejson=1.0.0",
+			err:  ErrBadInput,
+		},
+		{
+			name: "unknown tileset",
+			id:   "missing",
+			url:  "https://example.com/v1/missing",
+			err:  ErrNoEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tj, err := GetTileJSON(tt.id, u)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			got, err := json.Marshal(tj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("TileJSON =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/tileset"
+
+	"github.com/google/logger"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// TilesetInfo describes a tileset of the tileset index
+type TilesetInfo struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name,omitempty"`
+	Format   string     `json:"format"`
+	MinZoom  int        `json:"minzoom"`
+	MaxZoom  int        `json:"maxzoom"`
+	Bounds   [4]float64 `json:"bounds"`
+	Size     int64      `json:"size"`
+	Modified time.Time  `json:"modified"`
+	TileJSON string     `json:"tilejson"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// TilesetList represents a page of the tileset index
+type TilesetList struct {
+	Total    int            `json:"total"`
+	Offset   int            `json:"offset"`
+	Limit    int            `json:"limit"`
+	Tilesets []*TilesetInfo `json:"tilesets"`
+}
+
+// ListOptions defines the pagination and filter of the tileset index
+type ListOptions struct {
+	Offset int
+	Limit  int
+	Format mbtiles.TileFormat
+}
+
+// ParseListOptions parses the ListOptions from the query parameters
+// offset, limit and format
+func ParseListOptions(q url.Values) (*ListOptions, error) {
+	opts := &ListOptions{Limit: defaultListLimit}
+
+	if v := q.Get("offset"); len(v) > 0 {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("%w: offset must be a non-negative integer", ErrBadInput)
+		}
+		opts.Offset = i
+	}
+
+	if v := q.Get("limit"); len(v) > 0 {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 1 || i > maxListLimit {
+			return nil, fmt.Errorf("%w: limit must be an integer between 1 and %v", ErrBadInput, maxListLimit)
+		}
+		opts.Limit = i
+	}
+
+	if v := q.Get("format"); len(v) > 0 {
+		f, err := mbtiles.ParseTileFormat(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadInput, err)
+		}
+		opts.Format = f
+	}
+
+	return opts, nil
+}
+
+// GetTilesetList returns the tilesets matching the options. The TileJSON
+// links are resolved relative to the given URL. Tilesets whose metadata can
+// not be read are listed with an error instead of failing the whole list.
+func GetTilesetList(opts *ListOptions, u *url.URL) *TilesetList {
+	list := &TilesetList{
+		Offset:   opts.Offset,
+		Limit:    opts.Limit,
+		Tilesets: make([]*TilesetInfo, 0, opts.Limit),
+	}
+
+	for _, id := range tileset.IDs() {
+		ts, err := tileset.Get(id)
+		if err != nil {
+			continue // removed in the meantime
+		}
+
+		if opts.Format != mbtiles.UNKNOWN && ts.TileFormat() != opts.Format {
+			ts.Release()
+			continue
+		}
+
+		list.Total++
+
+		if list.Total <= opts.Offset || len(list.Tilesets) >= opts.Limit {
+			ts.Release()
+			continue
+		}
+
+		list.Tilesets = append(list.Tilesets, newTilesetInfo(id, ts, u))
+		ts.Release()
+	}
+
+	return list
+}
+
+func newTilesetInfo(id string, ts *tileset.Handle, u *url.URL) *TilesetInfo {
+	info := &TilesetInfo{
+		ID:       id,
+		Format:   ts.TileFormat().String(),
+		Size:     ts.FileSize(),
+		Modified: ts.ModTime(),
+		TileJSON: u.ResolveReference(&url.URL{Path: id}).String(),
+	}
+
+	md, err := ts.GetMetadata()
+	if err != nil {
+		logger.Warningf("Could not read metadata of tileset \"%s\": %s", id, err)
+		info.Error = "metadata could not be read"
+		return info
+	}
+
+	info.Name = md.Name
+	info.MinZoom = md.MinZoom
+	info.MaxZoom = md.MaxZoom
+	info.Bounds = md.Bounds
+
+	return info
+}
@@ -0,0 +1,305 @@
+package model
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/core/tms"
+
+	"github.com/google/logger"
+)
+
+const (
+	wmtsVersion = "1.0.0"
+
+	// WMTSStyle is the only style supported by the layers
+	WMTSStyle = "default"
+
+	wmtsCRS               = "urn:ogc:def:crs:EPSG::3857"
+	wmtsWellKnownScaleSet = "urn:ogc:def:wkss:OGC:1.0:GoogleMapsCompatible"
+)
+
+// WMTS exception codes
+const (
+	WMTSMissingParameter      = "MissingParameterValue"
+	WMTSInvalidParameter      = "InvalidParameterValue"
+	WMTSOperationNotSupported = "OperationNotSupported"
+	WMTSTileOutOfRange        = "TileOutOfRange"
+)
+
+// WMTSCapabilities represents the WMTS GetCapabilities document
+type WMTSCapabilities struct {
+	XMLName            xml.Name               `xml:"Capabilities"`
+	XMLNS              string                 `xml:"xmlns,attr"`
+	XMLNSOWS           string                 `xml:"xmlns:ows,attr"`
+	XMLNSXLink         string                 `xml:"xmlns:xlink,attr"`
+	Version            string                 `xml:"version,attr"`
+	Title              string                 `xml:"ows:ServiceIdentification>ows:Title"`
+	ServiceType        string                 `xml:"ows:ServiceIdentification>ows:ServiceType"`
+	ServiceVersion     string                 `xml:"ows:ServiceIdentification>ows:ServiceTypeVersion"`
+	Operations         []wmtsOperation        `xml:"ows:OperationsMetadata>ows:Operation"`
+	Layers             []wmtsLayer            `xml:"Contents>Layer"`
+	TileMatrixSet      wmtsTileMatrixSet      `xml:"Contents>TileMatrixSet"`
+	ServiceMetadataURL wmtsServiceMetadataURL `xml:"ServiceMetadataURL"`
+}
+
+type wmtsOperation struct {
+	Name string        `xml:"name,attr"`
+	Get  []wmtsGetLink `xml:"ows:DCP>ows:HTTP>ows:Get"`
+}
+
+type wmtsGetLink struct {
+	Href     string `xml:"xlink:href,attr"`
+	Encoding string `xml:"ows:Constraint>ows:AllowedValues>ows:Value"`
+}
+
+type wmtsLayer struct {
+	Title             string                `xml:"ows:Title"`
+	Abstract          string                `xml:"ows:Abstract,omitempty"`
+	LowerCorner       string                `xml:"ows:WGS84BoundingBox>ows:LowerCorner"`
+	UpperCorner       string                `xml:"ows:WGS84BoundingBox>ows:UpperCorner"`
+	Identifier        string                `xml:"ows:Identifier"`
+	Style             wmtsStyle             `xml:"Style"`
+	Format            string                `xml:"Format"`
+	TileMatrixSetLink wmtsTileMatrixSetLink `xml:"TileMatrixSetLink"`
+	ResourceURL       wmtsResourceURL       `xml:"ResourceURL"`
+}
+
+type wmtsStyle struct {
+	IsDefault  bool   `xml:"isDefault,attr"`
+	Identifier string `xml:"ows:Identifier"`
+}
+
+type wmtsTileMatrixSetLink struct {
+	TileMatrixSet string             `xml:"TileMatrixSet"`
+	Limits        []wmtsMatrixLimits `xml:"TileMatrixSetLimits>TileMatrixLimits"`
+}
+
+type wmtsMatrixLimits struct {
+	TileMatrix string `xml:"TileMatrix"`
+	MinTileRow uint64 `xml:"MinTileRow"`
+	MaxTileRow uint64 `xml:"MaxTileRow"`
+	MinTileCol uint64 `xml:"MinTileCol"`
+	MaxTileCol uint64 `xml:"MaxTileCol"`
+}
+
+type wmtsResourceURL struct {
+	Format       string `xml:"format,attr"`
+	ResourceType string `xml:"resourceType,attr"`
+	Template     string `xml:"template,attr"`
+}
+
+type wmtsTileMatrixSet struct {
+	Identifier        string           `xml:"ows:Identifier"`
+	SupportedCRS      string           `xml:"ows:SupportedCRS"`
+	WellKnownScaleSet string           `xml:"WellKnownScaleSet"`
+	TileMatrices      []wmtsTileMatrix `xml:"TileMatrix"`
+}
+
+type wmtsTileMatrix struct {
+	Identifier       string  `xml:"ows:Identifier"`
+	ScaleDenominator float64 `xml:"ScaleDenominator"`
+	TopLeftCorner    string  `xml:"TopLeftCorner"`
+	TileWidth        int     `xml:"TileWidth"`
+	TileHeight       int     `xml:"TileHeight"`
+	MatrixWidth      uint64  `xml:"MatrixWidth"`
+	MatrixHeight     uint64  `xml:"MatrixHeight"`
+}
+
+type wmtsServiceMetadataURL struct {
+	Href string `xml:"xlink:href,attr"`
+}
+
+// GetWMTSCapabilities returns the capabilities of the WMTS at the given URL
+func GetWMTSCapabilities(base *url.URL) (*WMTSCapabilities, error) {
+	kvpURL := base.String() + "?"
+	restURL := resolve(base, wmtsVersion)
+
+	c := &WMTSCapabilities{
+		XMLNS:          "http://www.opengis.net/wmts/1.0",
+		XMLNSOWS:       "http://www.opengis.net/ows/1.1",
+		XMLNSXLink:     "http://www.w3.org/1999/xlink",
+		Version:        wmtsVersion,
+		Title:          ogcTitle,
+		ServiceType:    "OGC WMTS",
+		ServiceVersion: wmtsVersion,
+		Operations: []wmtsOperation{
+			{
+				Name: "GetCapabilities",
+				Get: []wmtsGetLink{
+					{Href: kvpURL, Encoding: "KVP"},
+					{Href: restURL + "/WMTSCapabilities.xml", Encoding: "RESTful"},
+				},
+			},
+			{
+				Name: "GetTile",
+				Get: []wmtsGetLink{
+					{Href: kvpURL, Encoding: "KVP"},
+					{Href: restURL + "/", Encoding: "RESTful"},
+				},
+			},
+		},
+		Layers: make([]wmtsLayer, 0),
+		TileMatrixSet: wmtsTileMatrixSet{
+			Identifier:        tms.ID,
+			SupportedCRS:      wmtsCRS,
+			WellKnownScaleSet: wmtsWellKnownScaleSet,
+			TileMatrices:      make([]wmtsTileMatrix, 0, tms.MaxZoom+1),
+		},
+		ServiceMetadataURL: wmtsServiceMetadataURL{
+			Href: restURL + "/WMTSCapabilities.xml",
+		},
+	}
+
+	for _, id := range tileset.IDs() {
+		l, err := newWMTSLayer(id, restURL)
+		if errors.Is(err, ErrNoEntity) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			logger.Warningf("Could not list tileset \"%s\" as layer: %s", id, err)
+			continue
+		}
+
+		c.Layers = append(c.Layers, *l)
+	}
+
+	for z := uint8(0); z <= tms.MaxZoom; z++ {
+		m := tms.NewTileMatrix(z)
+		c.TileMatrixSet.TileMatrices = append(c.TileMatrixSet.TileMatrices, wmtsTileMatrix{
+			Identifier:       m.ID,
+			ScaleDenominator: m.ScaleDenominator,
+			TopLeftCorner:    fmt.Sprintf("%f %f", m.PointOfOrigin[0], m.PointOfOrigin[1]),
+			TileWidth:        m.TileWidth,
+			TileHeight:       m.TileHeight,
+			MatrixWidth:      m.MatrixWidth,
+			MatrixHeight:     m.MatrixHeight,
+		})
+	}
+
+	return c, nil
+}
+
+func newWMTSLayer(id, restURL string) (*wmtsLayer, error) {
+	ts, err := getTileset(id)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
+
+	md, err := ts.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	format := ts.TileFormat()
+
+	bounds := md.Bounds
+	if bounds == [4]float64{} {
+		bounds = [4]float64{-180, -tms.MaxLatitude, 180, tms.MaxLatitude}
+	}
+
+	title := md.Name
+	if title == "" {
+		title = id
+	}
+
+	l := &wmtsLayer{
+		Title:       title,
+		Abstract:    md.Description,
+		LowerCorner: fmt.Sprintf("%f %f", bounds[0], bounds[1]),
+		UpperCorner: fmt.Sprintf("%f %f", bounds[2], bounds[3]),
+		Identifier:  id,
+		Style:       wmtsStyle{IsDefault: true, Identifier: WMTSStyle},
+		Format:      format.ContentType(),
+		TileMatrixSetLink: wmtsTileMatrixSetLink{
+			TileMatrixSet: tms.ID,
+		},
+		ResourceURL: wmtsResourceURL{
+			Format:       format.ContentType(),
+			ResourceType: "tile",
+			Template: fmt.Sprintf("%s/%s/%s/{TileMatrixSet}/{TileMatrix}/{TileRow}/{TileCol}.%s",
+				restURL, url.PathEscape(id), WMTSStyle, format),
+		},
+	}
+
+	for z := md.MinZoom; z <= md.MaxZoom && z <= tms.MaxZoom; z++ {
+		lim := newTileMatrixSetLimit(md.Bounds, uint8(z))
+		l.TileMatrixSetLink.Limits = append(l.TileMatrixSetLink.Limits, wmtsMatrixLimits{
+			TileMatrix: lim.TileMatrix,
+			MinTileRow: lim.MinTileRow,
+			MaxTileRow: lim.MaxTileRow,
+			MinTileCol: lim.MinTileCol,
+			MaxTileCol: lim.MaxTileCol,
+		})
+	}
+
+	return l, nil
+}
+
+// WMTSException represents a WMTS exception report
+type WMTSException struct {
+	XMLName   xml.Name      `xml:"ExceptionReport"`
+	XMLNS     string        `xml:"xmlns,attr"`
+	Version   string        `xml:"version,attr"`
+	Exception wmtsException `xml:"Exception"`
+	HTTPCode  int           `xml:"-"`
+}
+
+type wmtsException struct {
+	Code    string `xml:"exceptionCode,attr"`
+	Locator string `xml:"locator,attr,omitempty"`
+	Text    string `xml:"ExceptionText"`
+}
+
+// NewWMTSException creates a new exception report
+func NewWMTSException(code, locator, text string) *WMTSException {
+	e := &WMTSException{
+		XMLNS:   "http://www.opengis.net/ows/1.1",
+		Version: "1.1.0",
+		Exception: wmtsException{
+			Code:    code,
+			Locator: locator,
+			Text:    text,
+		},
+	}
+
+	switch code {
+	case WMTSOperationNotSupported:
+		e.HTTPCode = http.StatusNotImplemented
+	case WMTSTileOutOfRange:
+		e.HTTPCode = http.StatusNotFound
+	default:
+		e.HTTPCode = http.StatusBadRequest
+	}
+
+	return e
+}
+
+// ValidateWMTSLayer checks the layer, style, tile matrix set and format
+// parameters of a GetTile request and returns an exception if they are invalid
+func ValidateWMTSLayer(layer, style, tmsID, format string) *WMTSException {
+	ts, err := tileset.Get(layer)
+	if err != nil {
+		return NewWMTSException(WMTSInvalidParameter, "layer", fmt.Sprintf("unknown layer %q", layer))
+	}
+	defer ts.Release()
+
+	if style != "" && style != WMTSStyle {
+		return NewWMTSException(WMTSInvalidParameter, "style", fmt.Sprintf("unknown style %q", style))
+	}
+
+	if tmsID != tms.ID {
+		return NewWMTSException(WMTSInvalidParameter, "tilematrixset", fmt.Sprintf("unknown tile matrix set %q", tmsID))
+	}
+
+	if format != "" && format != ts.TileFormat().ContentType() {
+		return NewWMTSException(WMTSInvalidParameter, "format", fmt.Sprintf("unsupported format %q", format))
+	}
+
+	return nil
+}
@@ -4,28 +4,79 @@ import (
 	"net/http"
 
 	cntrl "github.com/tarkov-database/tileserver/controller"
+	"github.com/tarkov-database/tileserver/core/config"
+	"github.com/tarkov-database/tileserver/middleware/accesslog"
 	"github.com/tarkov-database/tileserver/middleware/cors"
+	"github.com/tarkov-database/tileserver/middleware/metrics"
 
 	"github.com/julienschmidt/httprouter"
 )
 
-const prefix = "/v1"
+const (
+	prefix       = "/v1"
+	ogcPrefix    = "/ogc"
+	wmtsPrefix   = "/wmts"
+	healthPrefix = "/health"
+
+	// tilesetsPath is the path of the tileset listing below prefix
+	tilesetsPath = "tilesets"
+)
+
+// ReservedIDs are the tileset IDs that collide with other paths of the API
+var ReservedIDs = []string{tilesetsPath}
 
 // Load returns a router with defined routes
-func Load() *httprouter.Router {
-	return routes()
+func Load(cfg *config.Config) (*httprouter.Router, error) {
+	al, err := accesslog.New(cfg.AccessLog)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &middlewares{cors: cors.New(cfg.CORS), accessLog: al}
+
+	return routes(cfg, cntrl.New(cfg), m), nil
 }
 
-func routes() *httprouter.Router {
+func routes(cfg *config.Config, c *cntrl.Controller, m *middlewares) *httprouter.Router {
 	r := httprouter.New()
 
 	// Index
-	r.GET(prefix, middlwares(cntrl.IndexGET))
+	m.get(r, prefix, c.IndexGET)
 	r.Handler("GET", "/", http.RedirectHandler(prefix, http.StatusMovedPermanently))
 
 	// Tileset
-	r.GET(prefix+"/:id", middlwares(cntrl.TileJSONGET))
-	r.GET(prefix+"/:id/tiles/:z/:x/:y", middlwares(cntrl.TileGET))
+	m.get(r, prefix+"/:id", match("id", tilesetsPath, c.TilesetsGET, c.TileJSONGET))
+	m.get(r, prefix+"/:id/tiles/:z/:x/:y", c.TileGET)
+	m.get(r, prefix+"/:id/query", c.QueryGET)
+	m.get(r, prefix+"/:id/stats", c.StatsGET)
+
+	// Extract, disabled without size limit
+	if cfg.Tiles.ExtractMaxSize > 0 {
+		m.get(r, prefix+"/:id/extract", c.ExtractGET)
+	}
+
+	// OGC API - Tiles
+	m.get(r, ogcPrefix, c.LandingPageGET)
+	m.get(r, ogcPrefix+"/conformance", c.ConformanceGET)
+	m.get(r, ogcPrefix+"/tileMatrixSets", c.TileMatrixSetsGET)
+	m.get(r, ogcPrefix+"/tileMatrixSets/:tms", c.TileMatrixSetGET)
+	m.get(r, ogcPrefix+"/collections", c.CollectionsGET)
+	m.get(r, ogcPrefix+"/collections/:id", c.CollectionGET)
+	m.get(r, ogcPrefix+"/collections/:id/tiles", c.CollectionTileSetsGET)
+	m.get(r, ogcPrefix+"/collections/:id/tiles/:tms", c.CollectionTileSetGET)
+	m.get(r, ogcPrefix+"/collections/:id/tiles/:tms/:z/:row/:col", c.CollectionTileGET)
+
+	// WMTS
+	m.get(r, wmtsPrefix, c.WMTSGET)
+	m.get(r, wmtsPrefix+"/1.0.0/:layer", match("layer", "WMTSCapabilities.xml", c.WMTSCapabilitiesGET, notFound))
+	m.get(r, wmtsPrefix+"/1.0.0/:layer/:style/:tms/:z/:row/:col", c.WMTSTileGET)
+
+	// Health
+	r.GET(healthPrefix+"/live", c.LivenessGET)
+	r.GET(healthPrefix+"/ready", c.ReadinessGET)
+
+	// Metrics
+	r.GET("/metrics", metrics.MetricsGET)
 
 	r.RedirectTrailingSlash = true
 	r.HandleOPTIONS = true
@@ -33,6 +84,34 @@ func routes() *httprouter.Router {
 	return r
 }
 
-func middlwares(h httprouter.Handle) httprouter.Handle {
-	return cors.Handler(h)
+// match calls h if the path parameter has the given value and next otherwise.
+// It is needed because httprouter does not allow static path segments next
+// to parameters.
+func match(param, value string, h, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName(param) == value {
+			h(w, r, ps)
+			return
+		}
+
+		next(w, r, ps)
+	}
+}
+
+func notFound(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	http.NotFound(w, r)
+}
+
+type middlewares struct {
+	cors      *cors.CORS
+	accessLog *accesslog.Logger
+}
+
+// get registers the handle with the middlewares for GET requests of the path
+func (m *middlewares) get(r *httprouter.Router, path string, h httprouter.Handle) {
+	r.GET(path, m.wrap(path, h))
+}
+
+func (m *middlewares) wrap(route string, h httprouter.Handle) httprouter.Handle {
+	return m.cors.Handler(m.accessLog.Handler(metrics.Handler(route, h)))
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tarkov-database/tileserver/core/config"
+	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/server"
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/middleware/metrics"
+	"github.com/tarkov-database/tileserver/model"
+	"github.com/tarkov-database/tileserver/route"
+
+	"github.com/google/logger"
+)
+
+// serve starts the tile server
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML configuration file")
+	fs.Parse(args)
+
+	fmt.Printf("Starting up Tarkov Database TileServer\n\n")
+
+	defLog := logger.Init("default", true, false, io.Discard)
+	defer defLog.Close()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Errorf("Configuration error:\n%s", err)
+		os.Exit(2)
+	}
+
+	model.InitTileCache(cfg.Tiles.CacheSize)
+	model.SetMaxOverzoom(cfg.Tiles.MaxOverzoom)
+	model.SetExtractMaxSize(cfg.Tiles.ExtractMaxSize)
+	mbtiles.ObserveQueries(metrics.ObserveTileQuery)
+	metrics.SetState(metricsState{})
+	tileset.SetETagIndexing(cfg.Tiles.ETagIndex)
+
+	tileset.Reserve(route.ReservedIDs...)
+
+	composites, _ := cfg.Tiles.CompositeSources()
+	for id, sources := range composites {
+		tileset.RegisterComposite(id, sources)
+	}
+
+	if err := tileset.Load(cfg.Tiles.Dir); err != nil {
+		logger.Errorf("Tileset loading error: %v", err)
+		model.SetInitAsFailed()
+	}
+
+	go tileset.Watch(cfg.Tiles.Dir, cfg.Tiles.ReloadInterval)
+
+	router, err := route.Load(cfg)
+	if err != nil {
+		logger.Errorf("Router error: %s", err)
+		os.Exit(2)
+	}
+
+	if err := server.ListenAndServe(cfg.Server, router); err != nil {
+		logger.Errorf("HTTP server error: %s", err)
+	}
+}
+
+// metricsState exposes the state of the tilesets and the tile cache as metrics
+type metricsState struct{}
+
+func (metricsState) TilesetCounts() (loaded, failed int) {
+	return tileset.Counts()
+}
+
+func (metricsState) HasTileset(id string) bool {
+	ts, err := tileset.Get(id)
+	if err != nil {
+		return false
+	}
+	ts.Release()
+
+	return true
+}
+
+func (metricsState) CacheStats() (hits, misses uint64, size int64) {
+	s := model.GetCacheStats()
+	return s.Hits, s.Misses, s.Size
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tarkov-database/tileserver/core/tileset"
+)
+
+// validate opens all tilesets of the directory and runs the integrity checks
+// of their providers. It returns 1 if any problem was found.
+func validate(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: tileserver validate <dir>\n")
+		return 2
+	}
+
+	entries, err := os.ReadDir(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read directory: %s\n", err)
+		return 2
+	}
+
+	var checked, invalid int
+	for _, e := range entries {
+		if e.IsDir() || !tileset.IsSupported(e.Name()) {
+			continue
+		}
+		checked++
+
+		if problems := validateFile(filepath.Join(args[0], e.Name())); len(problems) > 0 {
+			invalid++
+			fmt.Printf("%s: %v problem(s)\n", e.Name(), len(problems))
+			for _, p := range problems {
+				fmt.Printf("  - %s\n", p)
+			}
+		} else {
+			fmt.Printf("%s: ok\n", e.Name())
+		}
+	}
+
+	fmt.Printf("\n%v tileset(s) checked, %v invalid\n", checked, invalid)
+
+	if invalid > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func validateFile(file string) []error {
+	p, err := tileset.Open(file)
+	if err != nil {
+		return []error{err}
+	}
+	defer p.Close()
+
+	if _, err := p.GetMetadata(); err != nil {
+		return []error{fmt.Errorf("could not read metadata: %w", err)}
+	}
+
+	if v, ok := p.(tileset.Validator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
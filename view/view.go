@@ -2,15 +2,25 @@ package view
 
 import (
 	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
 	"net/http"
 
-	"github.com/tarkov-database/tileserver/core/mbtiles"
+	"github.com/tarkov-database/tileserver/core/compression"
 	"github.com/tarkov-database/tileserver/model"
 
 	"github.com/google/logger"
 )
 
-const contentTypeJSON = "application/json"
+const (
+	contentTypeJSON = "application/json"
+	contentTypeXML  = "application/xml"
+
+	contentTypeJavaScript = "application/javascript"
+	contentTypeGeoJSON    = "application/geo+json"
+	contentTypeSQLite     = "application/vnd.sqlite3"
+)
 
 // RenderJSON encodes the input data into JSON and sends it as response
 func RenderJSON(w http.ResponseWriter, data interface{}, status int) {
@@ -22,10 +32,44 @@ func RenderJSON(w http.ResponseWriter, data interface{}, status int) {
 	}
 }
 
+// RenderGeoJSON encodes the input data into JSON and sends it as GeoJSON
+// response
+func RenderGeoJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", contentTypeGeoJSON)
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(&data); err != nil {
+		logger.Error(err)
+	}
+}
+
+// RenderXML encodes the input data into XML and sends it as response
+func RenderXML(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", contentTypeXML)
+	w.WriteHeader(status)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	if err := xml.NewEncoder(w).Encode(data); err != nil {
+		logger.Error(err)
+	}
+}
+
+// Download sends the extract as file attachment
+func Download(w http.ResponseWriter, r *http.Request, e *model.Extract) {
+	w.Header().Set("Content-Type", contentTypeSQLite)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": e.Name}))
+
+	http.ServeContent(w, r, e.Name, e.Modified, e.File)
+}
+
 func Tile(w http.ResponseWriter, t *model.Tile, status int) {
 	w.Header().Set("Content-Type", t.Format.ContentType())
-	if t.Format == mbtiles.PBF {
-		w.Header().Set("Content-Encoding", "gzip")
+	if t.Encoding != compression.Identity {
+		w.Header().Set("Content-Encoding", t.Encoding.String())
 	}
 	w.WriteHeader(status)
 
@@ -33,11 +77,13 @@ func Tile(w http.ResponseWriter, t *model.Tile, status int) {
 }
 
 func Grid(w http.ResponseWriter, t *model.Tile, status int) {
-	w.Header().Set("Content-Type", contentTypeJSON)
-	if t.Format == mbtiles.ZLIB {
-		w.Header().Set("Content-Encoding", "deflate")
+	if len(t.Callback) > 0 {
+		w.Header().Set("Content-Type", contentTypeJavaScript)
 	} else {
-		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", contentTypeJSON)
+	}
+	if t.Encoding != compression.Identity {
+		w.Header().Set("Content-Encoding", t.Encoding.String())
 	}
 	w.WriteHeader(status)
 
@@ -0,0 +1,97 @@
+package view
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tarkov-database/tileserver/core/tileset"
+	"github.com/tarkov-database/tileserver/model"
+)
+
+// rasterFixture creates an MBTiles file with the tile at 0/0/0 and returns
+// its path
+func rasterFixture(t *testing.T, format string, tile []byte) string {
+	file := filepath.Join(t.TempDir(), format+".mbtiles")
+
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE metadata (name text, value text)",
+		"CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)",
+		"INSERT INTO metadata VALUES ('name', 'raster'), ('minzoom', '0'), ('maxzoom', '0')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO tiles VALUES (0, 0, 0, ?)", tile); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestRasterTile(t *testing.T) {
+	tests := []struct {
+		format      string
+		tile        []byte
+		contentType string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"jpg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), "image/jpeg"},
+		{"webp", []byte("RIFF\xc0\x00\x00\x00WEBPVP8 "), "image/webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := tileset.Open(rasterFixture(t, tt.format, tt.tile))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+
+			id := "raster-" + tt.format
+			tileset.Register(id, p)
+			t.Cleanup(func() { tileset.Unregister(id) })
+
+			u := &url.URL{Scheme: "http", Host: "localhost", Path: "/v1/tileset/" + id}
+			tj, err := model.GetTileJSON(id, u)
+			if err != nil {
+				t.Fatalf("GetTileJSON() error = %v", err)
+			}
+			if len(tj.Tiles) != 1 || !strings.HasSuffix(tj.Tiles[0], "{z}/{x}/{y}."+tt.format) {
+				t.Errorf("TileJSON tiles = %v, want a template with the extension %q", tj.Tiles, tt.format)
+			}
+			if tj.VectorLayers != nil {
+				t.Errorf("TileJSON vector_layers = %v, want none", tj.VectorLayers)
+			}
+
+			tile, err := model.GetTile(id, "0", "0", "0")
+			if err != nil {
+				t.Fatalf("GetTile() error = %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			Tile(w, tile, http.StatusOK)
+
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q, want none", got)
+			}
+			if !bytes.Equal(w.Body.Bytes(), tt.tile) {
+				t.Errorf("body = %q, want %q", w.Body.Bytes(), tt.tile)
+			}
+		})
+	}
+}